@@ -2,21 +2,65 @@ package main
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"io"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/improbable-eng/grpc-web/go/grpcweb"
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/h2c"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/stats"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// maxBidiDelay caps the per-message delay a BidirectionalStream request can ask for.
+const maxBidiDelay = time.Minute
+
+// maxPayloadAlloc caps the payload LargePayload and BurstStream will
+// allocate, whatever -max-send-msg-size allows, so one request cannot
+// exhaust the server's memory.
+const maxPayloadAlloc = 64 << 20
+
+// BurstStream defaults when the request leaves count or size unset.
+const (
+	defaultBurstCount = 1000
+	defaultBurstSize  = 1024
 )
 
 type EchoServer struct {
 	UnimplementedEchoServiceServer
+
+	// maxSendMsgSize mirrors -max-send-msg-size so LargePayload can refuse
+	// oversized requests before allocating them.
+	maxSendMsgSize int
+	// burstStallThreshold is how long a single BurstStream Send may block
+	// before it is logged as a flow-control stall.
+	burstStallThreshold time.Duration
 }
 
 func (s *EchoServer) Echo(ctx context.Context, req *EchoRequest) (*EchoResponse, error) {
@@ -27,75 +71,309 @@ func (s *EchoServer) Echo(ctx context.Context, req *EchoRequest) (*EchoResponse,
 	}, nil
 }
 
+// EchoMetadata reports what reached the server alongside the message: the
+// incoming metadata, :authority, the deadline and the peer address, so
+// proxies can be checked for dropped headers and deadline propagation.
+func (s *EchoServer) EchoMetadata(ctx context.Context, req *EchoMetadataRequest) (*EchoMetadataResponse, error) {
+	resp := &EchoMetadataResponse{
+		Message:   req.Message,
+		Timestamp: time.Now().Unix(),
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	keys := make([]string, 0, len(md))
+	for k := range md {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		resp.Metadata = append(resp.Metadata, &MetadataEntry{Key: k, Values: md[k]})
+	}
+	if v := md.Get(":authority"); len(v) > 0 {
+		resp.Authority = v[0]
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		resp.HasDeadline = true
+		resp.Deadline = deadline.Format(time.RFC3339Nano)
+		resp.DeadlineRemainingMs = time.Until(deadline).Milliseconds()
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		resp.PeerAddress = p.Addr.String()
+	}
+
+	log.Printf("EchoMetadata request: %d metadata keys, authority=%q, deadline=%q, peer=%s",
+		len(resp.Metadata), resp.Authority, resp.Deadline, resp.PeerAddress)
+	return resp, nil
+}
+
+// ReturnError fails with the requested status code and message, optionally
+// with rich error details, to check that proxies pass gRPC statuses and
+// the grpc-status-details-bin trailer through unchanged.
+func (s *EchoServer) ReturnError(ctx context.Context, req *ErrorRequest) (*EchoResponse, error) {
+	code := codes.Code(req.Code)
+	if code == codes.OK {
+		return &EchoResponse{Message: req.Message, Timestamp: time.Now().Unix()}, nil
+	}
+	if code > codes.Unauthenticated {
+		return nil, status.Errorf(codes.InvalidArgument, "code %d is not a gRPC status code (1-16)", req.Code)
+	}
+	log.Printf("ReturnError request: code=%s message=%q details=%t", code, req.Message, req.WithDetails)
+
+	st := status.New(code, req.Message)
+	if !req.WithDetails {
+		return nil, st.Err()
+	}
+	withDetails, err := st.WithDetails(
+		&errdetails.ErrorInfo{
+			Reason:   "ARTIFICIAL_ERROR",
+			Domain:   "proxy-evals",
+			Metadata: map[string]string{"requested_code": code.String()},
+		},
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(time.Second)},
+		&errdetails.DebugInfo{Detail: "requested via EchoService/ReturnError"},
+	)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "attaching error details: %v", err)
+	}
+	return nil, withDetails.Err()
+}
+
+// LargePayload returns a payload of the requested size and reports how much
+// it received, for checking how proxies handle (or limit) large messages.
+// Sizes over -max-send-msg-size fail with ResourceExhausted, as gRPC itself
+// would when sending the response; so do sizes over maxPayloadAlloc.
+func (s *EchoServer) LargePayload(ctx context.Context, req *PayloadRequest) (*PayloadResponse, error) {
+	log.Printf("LargePayload request: size=%d received=%d", req.Size, len(req.Payload))
+	if req.Size < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "size must not be negative, got %d", req.Size)
+	}
+	if req.Size > int64(s.maxSendMsgSize) {
+		return nil, status.Errorf(codes.ResourceExhausted, "requested payload of %d bytes exceeds max send message size %d", req.Size, s.maxSendMsgSize)
+	}
+	if req.Size > maxPayloadAlloc {
+		return nil, status.Errorf(codes.ResourceExhausted, "requested payload of %d bytes exceeds the server's %d byte limit", req.Size, maxPayloadAlloc)
+	}
+
+	payload := make([]byte, req.Size)
+	for i := range payload {
+		payload[i] = 'a' + byte(i%26)
+	}
+	return &PayloadResponse{
+		Payload:      payload,
+		Size:         req.Size,
+		ReceivedSize: int64(len(req.Payload)),
+	}, nil
+}
+
+// BurstStream sends count messages back to back with no delay. gRPC still
+// honours HTTP/2 flow control, so a Send blocks once the peer's window is
+// used up: long blocks mean the proxy is pushing back, while a burst that
+// never blocks against a slow reader means the proxy is buffering it.
+func (s *EchoServer) BurstStream(req *BurstRequest, stream EchoService_BurstStreamServer) error {
+	count, size := req.Count, req.Size
+	if count <= 0 {
+		count = defaultBurstCount
+	}
+	if size <= 0 {
+		size = defaultBurstSize
+	}
+	if int(size) > s.maxSendMsgSize {
+		return status.Errorf(codes.ResourceExhausted, "message size %d exceeds max send message size %d", size, s.maxSendMsgSize)
+	}
+	if size > maxPayloadAlloc {
+		return status.Errorf(codes.ResourceExhausted, "message size %d exceeds the server's %d byte limit", size, maxPayloadAlloc)
+	}
+	log.Printf("BurstStream request: count=%d size=%d", count, size)
+
+	payload := make([]byte, size)
+	for i := range payload {
+		payload[i] = 'a' + byte(i%26)
+	}
+
+	start := time.Now()
+	var stalls int
+	var longest time.Duration
+	for i := int32(0); i < count; i++ {
+		sendStart := time.Now()
+		if err := stream.Send(&BurstResponse{
+			Index:          i,
+			Payload:        payload,
+			SentAtUnixNano: sendStart.UnixNano(),
+		}); err != nil {
+			log.Printf("BurstStream aborted after %d/%d messages in %s: %v", i, count, time.Since(start).Round(time.Millisecond), err)
+			if ctx := stream.Context(); ctx.Err() != nil {
+				return status.FromContextError(ctx.Err()).Err()
+			}
+			return err
+		}
+		blocked := time.Since(sendStart)
+		longest = max(longest, blocked)
+		if blocked >= s.burstStallThreshold {
+			stalls++
+			log.Printf("BurstStream stall: send #%d blocked for %s (%s into the burst)", i, blocked.Round(time.Millisecond), time.Since(start).Round(time.Millisecond))
+		}
+	}
+
+	elapsed := time.Since(start)
+	total := int64(count) * int64(size)
+	log.Printf("BurstStream completed: %d messages, %d bytes in %s (%.1f MB/s), %d stalls, longest send %s",
+		count, total, elapsed.Round(time.Millisecond), float64(total)/elapsed.Seconds()/1e6, stalls, longest.Round(time.Microsecond))
+	return nil
+}
+
+// ServerStream sends count messages delay_ms apart. It stops as soon as the
+// client or a proxy cancels the stream, logging how far it got.
 func (s *EchoServer) ServerStream(req *StreamRequest, stream EchoService_ServerStreamServer) error {
 	log.Printf("ServerStream request: count=%d", req.Count)
 
+	ctx := stream.Context()
+	delay := time.Duration(req.DelayMs) * time.Millisecond
 	for i := int32(0); i < req.Count; i++ {
 		if err := stream.Send(&StreamResponse{
 			Index:     i,
 			Message:   fmt.Sprintf("Message %d of %d", i+1, req.Count),
 			Timestamp: time.Now().Unix(),
 		}); err != nil {
+			if ctx.Err() != nil {
+				log.Printf("ServerStream cancelled after %d/%d messages: %v", i, req.Count, ctx.Err())
+				return status.FromContextError(ctx.Err()).Err()
+			}
 			return err
 		}
-		time.Sleep(time.Duration(req.DelayMs) * time.Millisecond)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			log.Printf("ServerStream cancelled after %d/%d messages: %v", i+1, req.Count, ctx.Err())
+			return status.FromContextError(ctx.Err()).Err()
+		}
 	}
 
 	return nil
 }
 
+// ClientStream collects the streamed messages and answers with their count,
+// sizes and a SHA-256 over them in arrival order, so a client can compare it
+// with its own to detect messages dropped, altered or reordered in transit.
 func (s *EchoServer) ClientStream(stream EchoService_ClientStreamServer) error {
 	var count int32
 	var messages []string
+	var lengths []int32
+	var total int64
+	sum := sha256.New()
 
 	for {
 		req, err := stream.Recv()
 		if err == io.EOF {
-			log.Printf("ClientStream completed: received %d messages", count)
+			checksum := hex.EncodeToString(sum.Sum(nil))
+			log.Printf("ClientStream completed: received %d messages, %d bytes, sha256=%s", count, total, checksum)
 			return stream.SendAndClose(&ClientStreamResponse{
-				Count:    count,
-				Messages: messages,
+				Count:          count,
+				Messages:       messages,
+				TotalBytes:     total,
+				MessageLengths: lengths,
+				Sha256:         checksum,
 			})
 		}
 		if err != nil {
+			if ctx := stream.Context(); ctx.Err() != nil {
+				log.Printf("ClientStream cancelled after %d messages: %v", count, ctx.Err())
+				return status.FromContextError(ctx.Err()).Err()
+			}
 			return err
 		}
 
 		count++
 		messages = append(messages, req.Message)
+		lengths = append(lengths, int32(len(req.Message)))
+		total += int64(len(req.Message))
+		sum.Write([]byte(req.Message))
 		log.Printf("ClientStream received: %s", req.Message)
 	}
 }
 
+// BidirectionalStream echoes each request with a server sequence number and
+// receive/send timestamps. A request's delay_ms holds back only its own
+// response, so a later request with a shorter delay is answered first; a
+// proxy that serializes the stream shows up as responses arriving in
+// request order.
 func (s *EchoServer) BidirectionalStream(stream EchoService_BidirectionalStreamServer) error {
 	log.Printf("BidirectionalStream started")
 
+	var (
+		sendMu  sync.Mutex
+		pending sync.WaitGroup
+		seq     int64
+	)
+	ctx := stream.Context()
+	send := func(req *ClientStreamRequest, seq int64, receivedAt time.Time) {
+		sendMu.Lock()
+		defer sendMu.Unlock()
+		now := time.Now()
+		err := stream.Send(&StreamResponse{
+			Index:              int32(seq),
+			Message:            "Echo: " + req.Message,
+			Timestamp:          now.Unix(),
+			Sequence:           seq,
+			ClientSequence:     req.Sequence,
+			ReceivedAtUnixNano: receivedAt.UnixNano(),
+			SentAtUnixNano:     now.UnixNano(),
+			DelayMs:            req.DelayMs,
+		})
+		if err != nil {
+			log.Printf("BidirectionalStream send #%d failed: %v", seq, err)
+		}
+	}
+
 	for {
 		req, err := stream.Recv()
+		receivedAt := time.Now()
 		if err == io.EOF {
-			log.Printf("BidirectionalStream completed")
-			return nil
+			// Delayed replies still pending when the stream is cancelled
+			// are dropped, so check again once they have finished.
+			pending.Wait()
+			if ctx.Err() == nil {
+				log.Printf("BidirectionalStream completed: %d messages", seq)
+				return nil
+			}
 		}
 		if err != nil {
+			pending.Wait()
+			if ctx.Err() != nil {
+				log.Printf("BidirectionalStream cancelled after %d messages: %v", seq, ctx.Err())
+				return status.FromContextError(ctx.Err()).Err()
+			}
 			return err
 		}
 
-		log.Printf("BidirectionalStream received: %s", req.Message)
-
-		resp := &StreamResponse{
-			Index:     0,
-			Message:   "Echo: " + req.Message,
-			Timestamp: time.Now().Unix(),
-		}
+		seq++
+		log.Printf("BidirectionalStream received #%d (client seq %d, delay %dms): %s", seq, req.Sequence, req.DelayMs, req.Message)
 
-		if err := stream.Send(resp); err != nil {
-			return err
+		if req.DelayMs <= 0 {
+			send(req, seq, receivedAt)
+			continue
 		}
+		delay := min(time.Duration(req.DelayMs)*time.Millisecond, maxBidiDelay)
+		pending.Add(1)
+		go func(req *ClientStreamRequest, seq int64) {
+			defer pending.Done()
+			select {
+			case <-time.After(delay - time.Since(receivedAt)):
+				send(req, seq, receivedAt)
+			case <-ctx.Done():
+			}
+		}(req, seq)
 	}
 }
 
 type HealthServer struct {
 	UnimplementedHealthServiceServer
+
+	// slowCheckDelay is used by SlowCheck when the request has no delay.
+	slowCheckDelay time.Duration
 }
 
 func (s *HealthServer) Check(ctx context.Context, req *HealthCheckRequest) (*HealthCheckResponse, error) {
@@ -104,6 +382,296 @@ func (s *HealthServer) Check(ctx context.Context, req *HealthCheckRequest) (*Hea
 	}, nil
 }
 
+// SlowCheck answers like Check after a delay, or fails with
+// DeadlineExceeded (or Canceled) if the caller gives up first.
+func (s *HealthServer) SlowCheck(ctx context.Context, req *SlowCheckRequest) (*HealthCheckResponse, error) {
+	delay := s.slowCheckDelay
+	if req.DelayMs > 0 {
+		delay = time.Duration(req.DelayMs) * time.Millisecond
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return &HealthCheckResponse{
+			Status: "SERVING",
+		}, nil
+	case <-ctx.Done():
+		log.Printf("SlowCheck gave up before %s delay: %v", delay, ctx.Err())
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+}
+
+// grpcWebOriginFunc allows cross-origin gRPC-Web calls from the listed
+// origins, or from any origin when the list contains "*". Same-origin calls
+// from the test page need no CORS and are always served.
+func grpcWebOriginFunc(allowed string) func(origin string) bool {
+	origins := make(map[string]bool)
+	for _, o := range strings.Split(allowed, ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			origins[o] = true
+		}
+	}
+	return func(origin string) bool {
+		return origins["*"] || origins[origin]
+	}
+}
+
+// jsonLogs switches RPC logs to structured JSON (-log-format=json).
+var jsonLogs bool
+
+func peerAddr(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok {
+		return p.Addr.String()
+	}
+	return "unknown"
+}
+
+// logRPC writes one access log line per finished call, in the same shape as
+// the http2 server's access log, so origin-side entries can be matched
+// against a proxy's gRPC logs (by x-request-id when the client sends one).
+func logRPC(ctx context.Context, method, kind string, start time.Time, err error) {
+	duration := time.Since(start)
+	st := status.Convert(err)
+	var requestID string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get("x-request-id"); len(ids) > 0 {
+			requestID = ids[0]
+		}
+	}
+
+	if jsonLogs {
+		attrs := []any{
+			"method", method,
+			"type", kind,
+			"peer", peerAddr(ctx),
+			"code", st.Code().String(),
+			"duration_ms", float64(duration.Microseconds()) / 1000,
+		}
+		if err != nil {
+			attrs = append(attrs, "error", st.Message())
+		}
+		if requestID != "" {
+			attrs = append(attrs, "request_id", requestID)
+		}
+		slog.Info("rpc", attrs...)
+		return
+	}
+
+	line := fmt.Sprintf("%s %s %s %s %s", method, kind, peerAddr(ctx), st.Code(), duration.Round(time.Microsecond))
+	if err != nil {
+		line += fmt.Sprintf(" (%s)", st.Message())
+	}
+	if requestID != "" {
+		line = "[" + requestID + "] " + line
+	}
+	log.Print(line)
+}
+
+func unaryLogger(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	logRPC(ctx, info.FullMethod, "unary", start, err)
+	return resp, err
+}
+
+func streamLogger(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	kind := "bidi_stream"
+	switch {
+	case info.IsClientStream && !info.IsServerStream:
+		kind = "client_stream"
+	case info.IsServerStream && !info.IsClientStream:
+		kind = "server_stream"
+	}
+	start := time.Now()
+	err := handler(srv, ss)
+	logRPC(ss.Context(), info.FullMethod, kind, start, err)
+	return err
+}
+
+// dialSelf returns a client for this server's own port using the transport
+// security it serves, so /health goes through the same TLS or h2c path as
+// proxied traffic.
+func dialSelf(port string, tlsEnabled bool) (*grpc.ClientConn, error) {
+	creds := insecure.NewCredentials()
+	if tlsEnabled {
+		// The certificate is typically self-signed or issued for the public
+		// name, so the loopback check exercises the handshake without
+		// verifying it.
+		creds = credentials.NewTLS(&tls.Config{InsecureSkipVerify: true})
+	}
+	return grpc.NewClient("localhost:"+port, grpc.WithTransportCredentials(creds))
+}
+
+// handleReadyz dials the server like /health, but asks the standard
+// grpc.health.v1 service, so it also goes unready when the status is set to
+// NOT_SERVING via /health/status. /livez only says the process is up.
+func handleReadyz(client healthpb.HealthClient, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+	resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		log.Printf("Readiness check over gRPC failed: %v", err)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"status": "unavailable", "error": err.Error()})
+		return
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"status": "unavailable", "grpc": resp.Status.String()})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok", "grpc": resp.Status.String()})
+}
+
+// handleHealth reports ok only if HealthService/Check answers over gRPC.
+func handleHealth(client HealthServiceClient, w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	w.Header().Set("Content-Type", "application/json")
+	resp, err := client.Check(ctx, &HealthCheckRequest{})
+	if err != nil {
+		log.Printf("Health check over gRPC failed: %v", err)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		json.NewEncoder(w).Encode(map[string]string{"status": "unavailable", "error": err.Error()})
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok", "grpc": resp.Status})
+}
+
+// serverConfig is what /config reports: the listeners, keepalive policy and
+// message size limits the gRPC server was started with. GRPCAddr is empty
+// when gRPC is only reachable over h2c on Addr.
+type serverConfig struct {
+	Server                       string `json:"server"`
+	Addr                         string `json:"addr"`
+	TLS                          bool   `json:"tls"`
+	GRPCAddr                     string `json:"grpc_addr,omitempty"`
+	Reflection                   bool   `json:"reflection"`
+	GRPCWebOrigins               string `json:"grpc_web_origins"`
+	LogFormat                    string `json:"log_format"`
+	KeepaliveTimeMs              int64  `json:"keepalive_time_ms"`
+	KeepaliveTimeoutMs           int64  `json:"keepalive_timeout_ms"`
+	MaxConnectionIdleMs          int64  `json:"max_connection_idle_ms"`
+	MaxConnectionAgeMs           int64  `json:"max_connection_age_ms"`
+	MaxConnectionAgeGraceMs      int64  `json:"max_connection_age_grace_ms"`
+	KeepaliveMinTimeMs           int64  `json:"keepalive_min_time_ms"`
+	KeepalivePermitWithoutStream bool   `json:"keepalive_permit_without_stream"`
+	MaxRecvMsgSize               int    `json:"max_recv_msg_size"`
+	MaxSendMsgSize               int    `json:"max_send_msg_size"`
+	SlowCheckDelayMs             int64  `json:"slow_check_delay_ms"`
+	BurstStallThresholdMs        int64  `json:"burst_stall_threshold_ms"`
+	InitialHealthStatus          string `json:"initial_health_status"`
+}
+
+func handleConfig(cfg serverConfig, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cfg)
+}
+
+// healthServices are the names whose grpc.health.v1 status is managed here;
+// "" is the overall server status most load balancers probe.
+var healthServices = []string{"", EchoService_ServiceDesc.ServiceName, HealthService_ServiceDesc.ServiceName}
+
+// handleHealthStatus shows (GET) or sets (POST ?status=NOT_SERVING) the
+// status reported by the standard grpc.health.v1.Health service, so health
+// checking in proxies and load balancers can be exercised without a restart.
+// POST applies to every service unless ?service= names one.
+func handleHealthStatus(hs *health.Server, w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		value, ok := healthpb.HealthCheckResponse_ServingStatus_value[strings.ToUpper(r.URL.Query().Get("status"))]
+		if !ok {
+			http.Error(w, "status must be one of SERVING, NOT_SERVING, UNKNOWN, SERVICE_UNKNOWN", http.StatusBadRequest)
+			return
+		}
+		st := healthpb.HealthCheckResponse_ServingStatus(value)
+		services := healthServices
+		if r.URL.Query().Has("service") {
+			services = []string{r.URL.Query().Get("service")}
+		}
+		for _, svc := range services {
+			hs.SetServingStatus(svc, st)
+			log.Printf("grpc.health.v1 status for %q set to %s", svc, st)
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	statuses := make(map[string]string)
+	for _, svc := range healthServices {
+		resp, err := hs.Check(r.Context(), &healthpb.HealthCheckRequest{Service: svc})
+		if err != nil {
+			statuses[svc] = status.Code(err).String()
+			continue
+		}
+		statuses[svc] = resp.Status.String()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(statuses)
+}
+
+// connLogger is a stats.Handler that logs gRPC connections as they open and
+// close, calling out closes that line up with -max-connection-age. grpc-go
+// jitters the age by ±10% and stats handlers never see the GOAWAY, so any
+// close of a native connection from 90% of maxAge on is attributed to it
+// (it may land as late as maxAge plus -max-connection-age-grace). RPCs
+// served over h2c on the main port go through ServeHTTP, which reports each
+// one as its own connection; max age is only enforced on nativePort.
+type connLogger struct {
+	maxAge     time.Duration
+	nativePort string
+}
+
+type connTagKey struct{}
+
+type connTag struct {
+	start  time.Time
+	native bool
+}
+
+func (l connLogger) TagConn(ctx context.Context, info *stats.ConnTagInfo) context.Context {
+	tag := connTag{start: time.Now()}
+	if l.nativePort != "" && info.LocalAddr != nil {
+		_, port, _ := net.SplitHostPort(info.LocalAddr.String())
+		tag.native = port == l.nativePort
+	}
+	return context.WithValue(ctx, connTagKey{}, tag)
+}
+
+func (l connLogger) HandleConn(ctx context.Context, s stats.ConnStats) {
+	addr := peerAddr(ctx)
+	switch s.(type) {
+	case *stats.ConnBegin:
+		log.Printf("gRPC connection opened from %s", addr)
+	case *stats.ConnEnd:
+		tag, _ := ctx.Value(connTagKey{}).(connTag)
+		age := time.Since(tag.start)
+		if tag.native && l.maxAge > 0 && age >= l.maxAge-l.maxAge/10 {
+			log.Printf("gRPC connection from %s closed after %s (at or after max connection age %s, ±10%% jitter)", addr, age.Round(time.Millisecond), l.maxAge)
+		} else {
+			log.Printf("gRPC connection from %s closed after %s", addr, age.Round(time.Millisecond))
+		}
+	}
+}
+
+func (l connLogger) TagRPC(ctx context.Context, info *stats.RPCTagInfo) context.Context {
+	return ctx
+}
+
+func (l connLogger) HandleRPC(ctx context.Context, s stats.RPCStats) {}
+
 const clientHTML = `<!DOCTYPE html>
 <html>
 <head>
@@ -131,8 +699,9 @@ const clientHTML = `<!DOCTYPE html>
     <h1>gRPC Test Client</h1>
 
     <div class="note">
-        <strong>Note:</strong> This is a web client. gRPC-Web requires a proxy (like Envoy) to translate HTTP/1.1 to gRPC.
-        For full gRPC testing, use a native gRPC client (grpcurl, Postman, or custom code).
+        <strong>Note:</strong> The buttons below call the server with gRPC-Web (application/grpc-web+proto over
+        HTTP/1.1 or HTTP/2), which the server translates in-process, so no Envoy is needed. Client and bidirectional
+        streaming are not available over gRPC-Web; use a native gRPC client (grpcurl, Postman, or custom code) for those.
     </div>
 
     <div class="test-section">
@@ -173,7 +742,7 @@ const clientHTML = `<!DOCTYPE html>
         <ul>
             <li>gRPC uses HTTP/2 as transport</li>
             <li>Standard gRPC requires HTTP/2 and binary protobuf</li>
-            <li>Web browsers need gRPC-Web with a translation proxy</li>
+            <li>Web browsers need gRPC-Web; this server translates it itself (POST /EchoService/...)</li>
         </ul>
         <p><strong>To test this server:</strong> Use <code>grpcurl</code> or similar gRPC client tools.</p>
         <pre style="background: #f5f5f5; padding: 10px; border-radius: 4px;">
@@ -183,8 +752,35 @@ grpcurl -plaintext localhost:50051 list
 # Call Echo
 grpcurl -plaintext -d '{"message":"hello"}' localhost:50051 EchoService/Echo
 
+# Call Echo over TLS (server started with -cert/-key)
+grpcurl -insecure -d '{"message":"hello"}' localhost:50051 EchoService/Echo
+
+# Echo metadata and deadline
+grpcurl -plaintext -H 'x-test: 1' -max-time 5 -d '{"message":"hello"}' localhost:50051 EchoService/EchoMetadata
+
+# Fail with a status code and rich error details
+grpcurl -plaintext -d '{"code":14,"message":"try later","with_details":true}' localhost:50051 EchoService/ReturnError
+
+# Health check that answers after a delay (fails with DeadlineExceeded under -max-time)
+grpcurl -plaintext -max-time 1 -d '{"delay_ms":3000}' localhost:50051 HealthService/SlowCheck
+
+# Large response (fails with ResourceExhausted above -max-send-msg-size)
+grpcurl -plaintext -d '{"size":3000000}' localhost:50051 EchoService/LargePayload
+
+# Standard health check (flip it with: curl -X POST 'localhost:50051/health/status?status=NOT_SERVING')
+grpcurl -plaintext -d '{"service":""}' localhost:50051 grpc.health.v1.Health/Check
+
+# Client stream: compare sha256 with: printf 'abc' | sha256sum
+grpcurl -plaintext -d '{"message":"a"} {"message":"bc"}' localhost:50051 EchoService/ClientStream
+
+# Burst of messages sent as fast as flow control allows (server logs stalls)
+grpcurl -plaintext -d '{"count":10000,"size":16384}' localhost:50051 EchoService/BurstStream > /dev/null
+
 # Server stream
 grpcurl -plaintext -d '{"count":5,"delay_ms":500}' localhost:50051 EchoService/ServerStream
+
+# Bidi timing: the second reply arrives first (compare sequence and sent_at_unix_nano)
+grpcurl -plaintext -d '{"message":"slow","sequence":1,"delay_ms":1000} {"message":"fast","sequence":2}' localhost:50051 EchoService/BidirectionalStream
         </pre>
     </div>
 
@@ -198,30 +794,235 @@ grpcurl -plaintext -d '{"count":5,"delay_ms":500}' localhost:50051 EchoService/S
             logEl.scrollTop = logEl.scrollHeight;
         }
 
-        function testEcho() {
-            document.getElementById('echoResult').textContent = 'This requires a gRPC client. Use grpcurl or similar tools.';
-            log('Use: grpcurl -plaintext -d \'{"message":"hello"}\' host:port EchoService/Echo', 'info');
+        // Minimal protobuf encoding for the few fields these calls use.
+        function varint(n) {
+            const out = [];
+            while (n > 127) {
+                out.push((n % 128) | 128);
+                n = Math.floor(n / 128);
+            }
+            out.push(n);
+            return out;
+        }
+
+        function stringField(num, str) {
+            const bytes = new TextEncoder().encode(str);
+            return [(num << 3) | 2, ...varint(bytes.length), ...bytes];
+        }
+
+        function intField(num, n) {
+            return n > 0 ? [num << 3, ...varint(n)] : [];
+        }
+
+        // Decodes a message into {fieldNumber: value}; strings stay strings,
+        // varints become numbers (exact up to 2^53, enough for timestamps).
+        function decode(bytes) {
+            const fields = {};
+            let i = 0;
+            const readVarint = () => {
+                let n = 0, mul = 1, b;
+                do {
+                    b = bytes[i++];
+                    n += (b & 127) * mul;
+                    mul *= 128;
+                } while (b & 128);
+                return n;
+            };
+            while (i < bytes.length) {
+                const key = readVarint();
+                const num = key >> 3, type = key & 7;
+                if (type === 0) {
+                    fields[num] = readVarint();
+                } else if (type === 2) {
+                    const len = readVarint();
+                    fields[num] = new TextDecoder().decode(bytes.subarray(i, i + len));
+                    i += len;
+                } else {
+                    throw new Error('unsupported wire type ' + type);
+                }
+            }
+            return fields;
+        }
+
+        // grpcWebCall POSTs one length-prefixed message and calls onMessage
+        // for every response frame as it arrives. It resolves with the
+        // grpc-status/grpc-message from the trailers (or headers, for
+        // trailers-only responses).
+        async function grpcWebCall(method, message, onMessage) {
+            const body = new Uint8Array(5 + message.length);
+            new DataView(body.buffer).setUint32(1, message.length);
+            body.set(message, 5);
+
+            const resp = await fetch('/' + method, {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/grpc-web+proto', 'X-Grpc-Web': '1' },
+                body: body,
+            });
+            let status = { code: resp.headers.get('grpc-status'), message: resp.headers.get('grpc-message') };
+            if (!resp.ok) {
+                return { code: 'HTTP ' + resp.status, message: resp.statusText };
+            }
+
+            const reader = resp.body.getReader();
+            let buf = new Uint8Array(0);
+            for (;;) {
+                const { done, value } = await reader.read();
+                if (done) break;
+                const merged = new Uint8Array(buf.length + value.length);
+                merged.set(buf);
+                merged.set(value, buf.length);
+                buf = merged;
+                while (buf.length >= 5) {
+                    const len = new DataView(buf.buffer, buf.byteOffset).getUint32(1);
+                    if (buf.length < 5 + len) break;
+                    const flags = buf[0], payload = buf.subarray(5, 5 + len);
+                    buf = buf.subarray(5 + len);
+                    if (flags & 0x80) {
+                        for (const line of new TextDecoder().decode(payload).split('\r\n')) {
+                            const idx = line.indexOf(':');
+                            if (idx < 0) continue;
+                            const key = line.slice(0, idx).trim().toLowerCase(), val = line.slice(idx + 1).trim();
+                            if (key === 'grpc-status') status.code = val;
+                            if (key === 'grpc-message') status.message = decodeURIComponent(val);
+                        }
+                    } else {
+                        onMessage(decode(payload));
+                    }
+                }
+            }
+            return status;
+        }
+
+        function statusText(st) {
+            return 'grpc-status: ' + st.code + (st.message ? ' (' + st.message + ')' : '');
+        }
+
+        async function testEcho() {
+            const out = document.getElementById('echoResult');
+            const msg = document.getElementById('echoMessage').value;
+            out.textContent = '';
+            log('gRPC-Web EchoService/Echo: ' + msg);
+            try {
+                const st = await grpcWebCall('EchoService/Echo', stringField(1, msg), (m) => {
+                    out.textContent += 'message: ' + (m[1] || '') + '\ntimestamp: ' + (m[2] || 0) + '\n';
+                });
+                out.textContent += statusText(st);
+                log('Echo finished, ' + statusText(st), st.code === '0' ? 'success' : 'error');
+            } catch (e) {
+                out.textContent += 'Error: ' + e.message;
+                log('Echo failed: ' + e.message, 'error');
+            }
         }
 
-        function testServerStream() {
-            document.getElementById('streamResult').textContent = 'This requires a gRPC client. Use grpcurl or similar tools.';
-            log('Use: grpcurl -plaintext -d \'{"count":5,"delay_ms":500}\' host:port EchoService/ServerStream', 'info');
+        async function testServerStream() {
+            const out = document.getElementById('streamResult');
+            const count = parseInt(document.getElementById('streamCount').value, 10) || 0;
+            const delay = parseInt(document.getElementById('streamDelay').value, 10) || 0;
+            out.textContent = '';
+            log('gRPC-Web EchoService/ServerStream: count=' + count + ' delay_ms=' + delay);
+            const req = new Uint8Array([...intField(1, count), ...intField(2, delay)]);
+            try {
+                const st = await grpcWebCall('EchoService/ServerStream', req, (m) => {
+                    out.textContent += '[' + (m[1] || 0) + '] ' + (m[2] || '') + '\n';
+                    log('Stream message ' + (m[1] || 0) + ': ' + (m[2] || ''));
+                });
+                out.textContent += statusText(st);
+                log('ServerStream finished, ' + statusText(st), st.code === '0' ? 'success' : 'error');
+            } catch (e) {
+                out.textContent += 'Error: ' + e.message;
+                log('ServerStream failed: ' + e.message, 'error');
+            }
         }
 
-        log('gRPC server is running. Use grpcurl or native gRPC clients to test.');
-        log('Example: grpcurl -plaintext ' + window.location.hostname + ':50051 list', 'success');
+        log('gRPC server is running. The buttons above use gRPC-Web against this origin.');
+        log('Native clients: grpcurl -plaintext ' + window.location.host + ' list', 'success');
     </script>
 </body>
 </html>`
 
 func main() {
 	port := flag.String("port", "8080", "Server port (serves both gRPC and HTTP)")
+	enableReflection := flag.Bool("reflection", true, "Register the gRPC server reflection service")
+	slowCheckDelay := flag.Duration("slow-check-delay", 2*time.Second, "Default HealthService/SlowCheck delay when the request sets none")
+	grpcPort := flag.String("grpc-port", "", "Optional port for a native gRPC listener, where the keepalive settings below are enforced")
+	keepaliveTime := flag.Duration("keepalive-time", 0, "Ping clients after this long without activity (0 = gRPC default, 2h)")
+	keepaliveTimeout := flag.Duration("keepalive-timeout", 0, "Close the connection if a keepalive ping is not answered within this (0 = gRPC default, 20s)")
+	maxConnIdle := flag.Duration("max-connection-idle", 0, "Send GOAWAY after a connection has had no RPCs for this long (0 = never)")
+	maxConnAge := flag.Duration("max-connection-age", 0, "Send GOAWAY once a connection is this old (0 = never)")
+	maxConnAgeGrace := flag.Duration("max-connection-age-grace", 0, "Time allowed for RPCs to finish after max-connection-age before closing (0 = forever)")
+	minPingInterval := flag.Duration("keepalive-min-time", 0, "Minimum interval clients may ping at before the server sends GOAWAY (0 = gRPC default, 5m)")
+	permitWithoutStream := flag.Bool("keepalive-permit-without-stream", false, "Allow client keepalive pings when there are no active RPCs")
+	maxRecvMsgSize := flag.Int("max-recv-msg-size", 4<<20, "Largest message in bytes the server accepts (larger ones fail with ResourceExhausted)")
+	maxSendMsgSize := flag.Int("max-send-msg-size", 4<<20, "Largest message in bytes the server sends (larger ones fail with ResourceExhausted)")
+	tlsCert := flag.String("cert", "", "TLS certificate file (serves gRPC and HTTP over TLS with ALPN h2)")
+	tlsKey := flag.String("key", "", "TLS key file")
+	grpcWebOrigins := flag.String("grpc-web-origins", "*", "Comma-separated origins allowed to make cross-origin gRPC-Web calls (* = any)")
+	logFormat := flag.String("log-format", "text", "Log output format: text or json")
+	burstStallThreshold := flag.Duration("burst-stall-threshold", 100*time.Millisecond, "Log a BurstStream send that blocks at least this long on flow control")
+	healthStatus := flag.String("health-status", "SERVING", "Initial grpc.health.v1 status: SERVING, NOT_SERVING or UNKNOWN (change at runtime via POST /health/status)")
 	flag.Parse()
 
-	grpcServer := grpc.NewServer()
-	RegisterEchoServiceServer(grpcServer, &EchoServer{})
-	RegisterHealthServiceServer(grpcServer, &HealthServer{})
-	reflection.Register(grpcServer)
+	if *logFormat == "json" {
+		jsonLogs = true
+		slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, nil)))
+	}
+
+	tlsEnabled := *tlsCert != "" || *tlsKey != ""
+	if tlsEnabled && (*tlsCert == "" || *tlsKey == "") {
+		log.Fatal("-cert and -key must be given together")
+	}
+
+	// gRPC applies these only on its own transport (-grpc-port). The h2c
+	// port is served by net/http, which only mirrors max-connection-idle
+	// (as http2.Server.IdleTimeout); pings and max age are not enforced there.
+	opts := []grpc.ServerOption{
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			MaxConnectionIdle:     *maxConnIdle,
+			MaxConnectionAge:      *maxConnAge,
+			MaxConnectionAgeGrace: *maxConnAgeGrace,
+			Time:                  *keepaliveTime,
+			Timeout:               *keepaliveTimeout,
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             *minPingInterval,
+			PermitWithoutStream: *permitWithoutStream,
+		}),
+		grpc.StatsHandler(connLogger{maxAge: *maxConnAge, nativePort: *grpcPort}),
+		grpc.MaxRecvMsgSize(*maxRecvMsgSize),
+		grpc.MaxSendMsgSize(*maxSendMsgSize),
+		grpc.UnaryInterceptor(unaryLogger),
+		grpc.StreamInterceptor(streamLogger),
+	}
+	// Creds only affect the native listener; the main port terminates TLS
+	// in net/http and hands requests to ServeHTTP.
+	if tlsEnabled {
+		creds, err := credentials.NewServerTLSFromFile(*tlsCert, *tlsKey)
+		if err != nil {
+			log.Fatalf("Failed to load TLS credentials: %v", err)
+		}
+		opts = append(opts, grpc.Creds(creds))
+	}
+	grpcServer := grpc.NewServer(opts...)
+	log.Printf("Message size limits: recv=%d send=%d bytes", *maxRecvMsgSize, *maxSendMsgSize)
+	RegisterEchoServiceServer(grpcServer, &EchoServer{maxSendMsgSize: *maxSendMsgSize, burstStallThreshold: *burstStallThreshold})
+	RegisterHealthServiceServer(grpcServer, &HealthServer{slowCheckDelay: *slowCheckDelay})
+
+	// The standard health protocol sits alongside the custom HealthService.
+	initialStatus, ok := healthpb.HealthCheckResponse_ServingStatus_value[strings.ToUpper(*healthStatus)]
+	if !ok {
+		log.Fatalf("Unknown -health-status %q", *healthStatus)
+	}
+	healthSrv := health.NewServer()
+	for _, svc := range healthServices {
+		healthSrv.SetServingStatus(svc, healthpb.HealthCheckResponse_ServingStatus(initialStatus))
+	}
+	healthpb.RegisterHealthServer(grpcServer, healthSrv)
+	log.Printf("grpc.health.v1 status: %s", healthpb.HealthCheckResponse_ServingStatus(initialStatus))
+	if *enableReflection {
+		reflection.Register(grpcServer)
+	} else {
+		log.Printf("Server reflection disabled")
+	}
 
 	httpMux := http.NewServeMux()
 	httpMux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
@@ -229,20 +1030,68 @@ func main() {
 		w.Write([]byte(clientHTML))
 	})
 
+	healthConn, err := dialSelf(*port, tlsEnabled)
+	if err != nil {
+		log.Fatalf("Failed to create health check client: %v", err)
+	}
+	defer healthConn.Close()
+	healthClient := NewHealthServiceClient(healthConn)
 	httpMux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		handleHealth(healthClient, w, r)
+	})
+	cfg := serverConfig{
+		Server:                       "grpc",
+		Addr:                         ":" + *port,
+		TLS:                          tlsEnabled,
+		Reflection:                   *enableReflection,
+		GRPCWebOrigins:               *grpcWebOrigins,
+		LogFormat:                    *logFormat,
+		KeepaliveTimeMs:              keepaliveTime.Milliseconds(),
+		KeepaliveTimeoutMs:           keepaliveTimeout.Milliseconds(),
+		MaxConnectionIdleMs:          maxConnIdle.Milliseconds(),
+		MaxConnectionAgeMs:           maxConnAge.Milliseconds(),
+		MaxConnectionAgeGraceMs:      maxConnAgeGrace.Milliseconds(),
+		KeepaliveMinTimeMs:           minPingInterval.Milliseconds(),
+		KeepalivePermitWithoutStream: *permitWithoutStream,
+		MaxRecvMsgSize:               *maxRecvMsgSize,
+		MaxSendMsgSize:               *maxSendMsgSize,
+		SlowCheckDelayMs:             slowCheckDelay.Milliseconds(),
+		BurstStallThresholdMs:        burstStallThreshold.Milliseconds(),
+		InitialHealthStatus:          healthpb.HealthCheckResponse_ServingStatus(initialStatus).String(),
+	}
+	if *grpcPort != "" {
+		cfg.GRPCAddr = ":" + *grpcPort
+	}
+	httpMux.HandleFunc("/config", func(w http.ResponseWriter, r *http.Request) {
+		handleConfig(cfg, w, r)
+	})
+	httpMux.HandleFunc("/livez", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.Write([]byte(`{"status":"ok"}`))
 	})
+	readyClient := healthpb.NewHealthClient(healthConn)
+	httpMux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		handleReadyz(readyClient, w, r)
+	})
+	httpMux.HandleFunc("/health/status", func(w http.ResponseWriter, r *http.Request) {
+		handleHealthStatus(healthSrv, w, r)
+	})
+
+	grpcWeb := grpcweb.WrapServer(grpcServer, grpcweb.WithOriginFunc(grpcWebOriginFunc(*grpcWebOrigins)))
 
 	mixedHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if r.ProtoMajor == 2 && r.Header.Get("Content-Type") == "application/grpc" {
+		switch {
+		case r.ProtoMajor == 2 && r.Header.Get("Content-Type") == "application/grpc":
 			grpcServer.ServeHTTP(w, r)
-		} else {
+		case grpcWeb.IsGrpcWebRequest(r) || grpcWeb.IsAcceptableGrpcCorsRequest(r):
+			log.Printf("gRPC-Web request: %s %s (%s, %s)", r.Method, r.URL.Path, r.Proto, r.Header.Get("Content-Type"))
+			grpcWeb.ServeHTTP(w, r)
+		default:
 			httpMux.ServeHTTP(w, r)
 		}
 	})
 
-	h2s := &http2.Server{}
+	h2s := &http2.Server{IdleTimeout: *maxConnIdle}
 	h2cHandler := h2c.NewHandler(mixedHandler, h2s)
 
 	server := &http.Server{
@@ -250,6 +1099,46 @@ func main() {
 		Handler: h2cHandler,
 	}
 
+	log.Printf("Keepalive: time=%s timeout=%s max-connection-idle=%s max-connection-age=%s grace=%s min-time=%s permit-without-stream=%t (0 = default)",
+		*keepaliveTime, *keepaliveTimeout, *maxConnIdle, *maxConnAge, *maxConnAgeGrace, *minPingInterval, *permitWithoutStream)
+	if *grpcPort == "" {
+		// Only max-connection-idle carries over to the h2c port, so say so
+		// rather than let the other flags look like they took effect.
+		var ignored []string
+		flag.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "keepalive-time", "keepalive-timeout", "max-connection-age", "max-connection-age-grace", "keepalive-min-time", "keepalive-permit-without-stream":
+				ignored = append(ignored, "-"+f.Name)
+			}
+		})
+		if len(ignored) > 0 {
+			log.Printf("Warning: %s only apply to the native gRPC listener and do nothing without -grpc-port", strings.Join(ignored, ", "))
+		}
+	}
+
+	if *grpcPort != "" {
+		lis, err := net.Listen("tcp", ":"+*grpcPort)
+		if err != nil {
+			log.Fatalf("Failed to listen on :%s: %v", *grpcPort, err)
+		}
+		go func() {
+			log.Printf("Starting native gRPC server on :%s", *grpcPort)
+			log.Fatal(grpcServer.Serve(lis))
+		}()
+	}
+
+	if tlsEnabled {
+		// Plain TLS with ALPN h2 (and http/1.1 for the browser page); h2c
+		// is only meaningful on cleartext connections.
+		server.Handler = mixedHandler
+		if err := http2.ConfigureServer(server, h2s); err != nil {
+			log.Fatalf("Failed to configure HTTP/2: %v", err)
+		}
+		log.Printf("Starting server on :%s (gRPC + HTTP/2 over TLS)", *port)
+		log.Fatal(server.ListenAndServeTLS(*tlsCert, *tlsKey))
+	}
+
+	log.Printf("No -cert/-key given, serving plaintext")
 	log.Printf("Starting server on :%s (gRPC + HTTP/2 via h2c)", *port)
 	log.Fatal(server.ListenAndServe())
 }
@@ -117,6 +117,495 @@ func (x *EchoResponse) GetTimestamp() int64 {
 	return 0
 }
 
+type EchoMetadataRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Message       string                 `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EchoMetadataRequest) Reset() {
+	*x = EchoMetadataRequest{}
+	mi := &file_service_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EchoMetadataRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EchoMetadataRequest) ProtoMessage() {}
+
+func (x *EchoMetadataRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EchoMetadataRequest.ProtoReflect.Descriptor instead.
+func (*EchoMetadataRequest) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *EchoMetadataRequest) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type MetadataEntry struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"`
+	Values        []string               `protobuf:"bytes,2,rep,name=values,proto3" json:"values,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MetadataEntry) Reset() {
+	*x = MetadataEntry{}
+	mi := &file_service_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MetadataEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MetadataEntry) ProtoMessage() {}
+
+func (x *MetadataEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MetadataEntry.ProtoReflect.Descriptor instead.
+func (*MetadataEntry) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *MetadataEntry) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *MetadataEntry) GetValues() []string {
+	if x != nil {
+		return x.Values
+	}
+	return nil
+}
+
+type EchoMetadataResponse struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Message   string                 `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	Timestamp int64                  `protobuf:"varint,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	// Incoming metadata sorted by key, including pseudo-headers such as
+	// :authority that gRPC exposes as metadata.
+	Metadata    []*MetadataEntry `protobuf:"bytes,3,rep,name=metadata,proto3" json:"metadata,omitempty"`
+	Authority   string           `protobuf:"bytes,4,opt,name=authority,proto3" json:"authority,omitempty"`
+	HasDeadline bool             `protobuf:"varint,5,opt,name=has_deadline,json=hasDeadline,proto3" json:"has_deadline,omitempty"`
+	// RFC 3339 time the deadline expires, and how long was left on arrival.
+	Deadline            string `protobuf:"bytes,6,opt,name=deadline,proto3" json:"deadline,omitempty"`
+	DeadlineRemainingMs int64  `protobuf:"varint,7,opt,name=deadline_remaining_ms,json=deadlineRemainingMs,proto3" json:"deadline_remaining_ms,omitempty"`
+	PeerAddress         string `protobuf:"bytes,8,opt,name=peer_address,json=peerAddress,proto3" json:"peer_address,omitempty"`
+	unknownFields       protoimpl.UnknownFields
+	sizeCache           protoimpl.SizeCache
+}
+
+func (x *EchoMetadataResponse) Reset() {
+	*x = EchoMetadataResponse{}
+	mi := &file_service_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EchoMetadataResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EchoMetadataResponse) ProtoMessage() {}
+
+func (x *EchoMetadataResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EchoMetadataResponse.ProtoReflect.Descriptor instead.
+func (*EchoMetadataResponse) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *EchoMetadataResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *EchoMetadataResponse) GetTimestamp() int64 {
+	if x != nil {
+		return x.Timestamp
+	}
+	return 0
+}
+
+func (x *EchoMetadataResponse) GetMetadata() []*MetadataEntry {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *EchoMetadataResponse) GetAuthority() string {
+	if x != nil {
+		return x.Authority
+	}
+	return ""
+}
+
+func (x *EchoMetadataResponse) GetHasDeadline() bool {
+	if x != nil {
+		return x.HasDeadline
+	}
+	return false
+}
+
+func (x *EchoMetadataResponse) GetDeadline() string {
+	if x != nil {
+		return x.Deadline
+	}
+	return ""
+}
+
+func (x *EchoMetadataResponse) GetDeadlineRemainingMs() int64 {
+	if x != nil {
+		return x.DeadlineRemainingMs
+	}
+	return 0
+}
+
+func (x *EchoMetadataResponse) GetPeerAddress() string {
+	if x != nil {
+		return x.PeerAddress
+	}
+	return ""
+}
+
+type PayloadRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Size in bytes of the payload to return.
+	Size int64 `protobuf:"varint,1,opt,name=size,proto3" json:"size,omitempty"`
+	// Optional data to send upstream; its length is reported back.
+	Payload       []byte `protobuf:"bytes,2,opt,name=payload,proto3" json:"payload,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PayloadRequest) Reset() {
+	*x = PayloadRequest{}
+	mi := &file_service_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PayloadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PayloadRequest) ProtoMessage() {}
+
+func (x *PayloadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PayloadRequest.ProtoReflect.Descriptor instead.
+func (*PayloadRequest) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *PayloadRequest) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *PayloadRequest) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+type PayloadResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Payload       []byte                 `protobuf:"bytes,1,opt,name=payload,proto3" json:"payload,omitempty"`
+	Size          int64                  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	ReceivedSize  int64                  `protobuf:"varint,3,opt,name=received_size,json=receivedSize,proto3" json:"received_size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PayloadResponse) Reset() {
+	*x = PayloadResponse{}
+	mi := &file_service_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PayloadResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PayloadResponse) ProtoMessage() {}
+
+func (x *PayloadResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PayloadResponse.ProtoReflect.Descriptor instead.
+func (*PayloadResponse) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *PayloadResponse) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *PayloadResponse) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *PayloadResponse) GetReceivedSize() int64 {
+	if x != nil {
+		return x.ReceivedSize
+	}
+	return 0
+}
+
+type BurstRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Number of messages to send (default 1000).
+	Count int32 `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	// Payload bytes per message (default 1024).
+	Size          int32 `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BurstRequest) Reset() {
+	*x = BurstRequest{}
+	mi := &file_service_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BurstRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BurstRequest) ProtoMessage() {}
+
+func (x *BurstRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BurstRequest.ProtoReflect.Descriptor instead.
+func (*BurstRequest) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *BurstRequest) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *BurstRequest) GetSize() int32 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+type BurstResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Index          int32                  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Payload        []byte                 `protobuf:"bytes,2,opt,name=payload,proto3" json:"payload,omitempty"`
+	SentAtUnixNano int64                  `protobuf:"varint,3,opt,name=sent_at_unix_nano,json=sentAtUnixNano,proto3" json:"sent_at_unix_nano,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *BurstResponse) Reset() {
+	*x = BurstResponse{}
+	mi := &file_service_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BurstResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BurstResponse) ProtoMessage() {}
+
+func (x *BurstResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BurstResponse.ProtoReflect.Descriptor instead.
+func (*BurstResponse) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *BurstResponse) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *BurstResponse) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+func (x *BurstResponse) GetSentAtUnixNano() int64 {
+	if x != nil {
+		return x.SentAtUnixNano
+	}
+	return 0
+}
+
+type ErrorRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// gRPC status code to fail with, 1-16. 0 (OK) succeeds.
+	Code    int32  `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	Message string `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	// Attach ErrorInfo, RetryInfo and DebugInfo details to the status.
+	WithDetails   bool `protobuf:"varint,3,opt,name=with_details,json=withDetails,proto3" json:"with_details,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ErrorRequest) Reset() {
+	*x = ErrorRequest{}
+	mi := &file_service_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ErrorRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ErrorRequest) ProtoMessage() {}
+
+func (x *ErrorRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ErrorRequest.ProtoReflect.Descriptor instead.
+func (*ErrorRequest) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ErrorRequest) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *ErrorRequest) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *ErrorRequest) GetWithDetails() bool {
+	if x != nil {
+		return x.WithDetails
+	}
+	return false
+}
+
 type StreamRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Count         int32                  `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
@@ -127,7 +616,7 @@ type StreamRequest struct {
 
 func (x *StreamRequest) Reset() {
 	*x = StreamRequest{}
-	mi := &file_service_proto_msgTypes[2]
+	mi := &file_service_proto_msgTypes[10]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -139,7 +628,7 @@ func (x *StreamRequest) String() string {
 func (*StreamRequest) ProtoMessage() {}
 
 func (x *StreamRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_service_proto_msgTypes[2]
+	mi := &file_service_proto_msgTypes[10]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -152,7 +641,7 @@ func (x *StreamRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StreamRequest.ProtoReflect.Descriptor instead.
 func (*StreamRequest) Descriptor() ([]byte, []int) {
-	return file_service_proto_rawDescGZIP(), []int{2}
+	return file_service_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *StreamRequest) GetCount() int32 {
@@ -170,17 +659,23 @@ func (x *StreamRequest) GetDelayMs() int32 {
 }
 
 type StreamResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Index         int32                  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
-	Timestamp     int64                  `protobuf:"varint,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Index     int32                  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Message   string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Timestamp int64                  `protobuf:"varint,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	// The fields below are only set by BidirectionalStream.
+	Sequence           int64 `protobuf:"varint,4,opt,name=sequence,proto3" json:"sequence,omitempty"`
+	ClientSequence     int64 `protobuf:"varint,5,opt,name=client_sequence,json=clientSequence,proto3" json:"client_sequence,omitempty"`
+	ReceivedAtUnixNano int64 `protobuf:"varint,6,opt,name=received_at_unix_nano,json=receivedAtUnixNano,proto3" json:"received_at_unix_nano,omitempty"`
+	SentAtUnixNano     int64 `protobuf:"varint,7,opt,name=sent_at_unix_nano,json=sentAtUnixNano,proto3" json:"sent_at_unix_nano,omitempty"`
+	DelayMs            int32 `protobuf:"varint,8,opt,name=delay_ms,json=delayMs,proto3" json:"delay_ms,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
 }
 
 func (x *StreamResponse) Reset() {
 	*x = StreamResponse{}
-	mi := &file_service_proto_msgTypes[3]
+	mi := &file_service_proto_msgTypes[11]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -192,7 +687,7 @@ func (x *StreamResponse) String() string {
 func (*StreamResponse) ProtoMessage() {}
 
 func (x *StreamResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_service_proto_msgTypes[3]
+	mi := &file_service_proto_msgTypes[11]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -205,7 +700,7 @@ func (x *StreamResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use StreamResponse.ProtoReflect.Descriptor instead.
 func (*StreamResponse) Descriptor() ([]byte, []int) {
-	return file_service_proto_rawDescGZIP(), []int{3}
+	return file_service_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *StreamResponse) GetIndex() int32 {
@@ -229,16 +724,55 @@ func (x *StreamResponse) GetTimestamp() int64 {
 	return 0
 }
 
+func (x *StreamResponse) GetSequence() int64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
+func (x *StreamResponse) GetClientSequence() int64 {
+	if x != nil {
+		return x.ClientSequence
+	}
+	return 0
+}
+
+func (x *StreamResponse) GetReceivedAtUnixNano() int64 {
+	if x != nil {
+		return x.ReceivedAtUnixNano
+	}
+	return 0
+}
+
+func (x *StreamResponse) GetSentAtUnixNano() int64 {
+	if x != nil {
+		return x.SentAtUnixNano
+	}
+	return 0
+}
+
+func (x *StreamResponse) GetDelayMs() int32 {
+	if x != nil {
+		return x.DelayMs
+	}
+	return 0
+}
+
 type ClientStreamRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Message       string                 `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	Message string                 `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	// Used by BidirectionalStream: the response is sent delay_ms after the
+	// request arrives, and sequence is echoed back as client_sequence.
+	DelayMs       int32 `protobuf:"varint,2,opt,name=delay_ms,json=delayMs,proto3" json:"delay_ms,omitempty"`
+	Sequence      int64 `protobuf:"varint,3,opt,name=sequence,proto3" json:"sequence,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ClientStreamRequest) Reset() {
 	*x = ClientStreamRequest{}
-	mi := &file_service_proto_msgTypes[4]
+	mi := &file_service_proto_msgTypes[12]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -250,7 +784,7 @@ func (x *ClientStreamRequest) String() string {
 func (*ClientStreamRequest) ProtoMessage() {}
 
 func (x *ClientStreamRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_service_proto_msgTypes[4]
+	mi := &file_service_proto_msgTypes[12]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -263,7 +797,7 @@ func (x *ClientStreamRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ClientStreamRequest.ProtoReflect.Descriptor instead.
 func (*ClientStreamRequest) Descriptor() ([]byte, []int) {
-	return file_service_proto_rawDescGZIP(), []int{4}
+	return file_service_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *ClientStreamRequest) GetMessage() string {
@@ -273,17 +807,36 @@ func (x *ClientStreamRequest) GetMessage() string {
 	return ""
 }
 
+func (x *ClientStreamRequest) GetDelayMs() int32 {
+	if x != nil {
+		return x.DelayMs
+	}
+	return 0
+}
+
+func (x *ClientStreamRequest) GetSequence() int64 {
+	if x != nil {
+		return x.Sequence
+	}
+	return 0
+}
+
 type ClientStreamResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Count         int32                  `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
-	Messages      []string               `protobuf:"bytes,2,rep,name=messages,proto3" json:"messages,omitempty"`
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Count    int32                  `protobuf:"varint,1,opt,name=count,proto3" json:"count,omitempty"`
+	Messages []string               `protobuf:"bytes,2,rep,name=messages,proto3" json:"messages,omitempty"`
+	// Length in bytes of all messages together, and of each in arrival order.
+	TotalBytes     int64   `protobuf:"varint,3,opt,name=total_bytes,json=totalBytes,proto3" json:"total_bytes,omitempty"`
+	MessageLengths []int32 `protobuf:"varint,4,rep,packed,name=message_lengths,json=messageLengths,proto3" json:"message_lengths,omitempty"`
+	// Hex SHA-256 of the messages concatenated in arrival order.
+	Sha256        string `protobuf:"bytes,5,opt,name=sha256,proto3" json:"sha256,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ClientStreamResponse) Reset() {
 	*x = ClientStreamResponse{}
-	mi := &file_service_proto_msgTypes[5]
+	mi := &file_service_proto_msgTypes[13]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -295,7 +848,7 @@ func (x *ClientStreamResponse) String() string {
 func (*ClientStreamResponse) ProtoMessage() {}
 
 func (x *ClientStreamResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_service_proto_msgTypes[5]
+	mi := &file_service_proto_msgTypes[13]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -308,7 +861,7 @@ func (x *ClientStreamResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ClientStreamResponse.ProtoReflect.Descriptor instead.
 func (*ClientStreamResponse) Descriptor() ([]byte, []int) {
-	return file_service_proto_rawDescGZIP(), []int{5}
+	return file_service_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *ClientStreamResponse) GetCount() int32 {
@@ -325,6 +878,27 @@ func (x *ClientStreamResponse) GetMessages() []string {
 	return nil
 }
 
+func (x *ClientStreamResponse) GetTotalBytes() int64 {
+	if x != nil {
+		return x.TotalBytes
+	}
+	return 0
+}
+
+func (x *ClientStreamResponse) GetMessageLengths() []int32 {
+	if x != nil {
+		return x.MessageLengths
+	}
+	return nil
+}
+
+func (x *ClientStreamResponse) GetSha256() string {
+	if x != nil {
+		return x.Sha256
+	}
+	return ""
+}
+
 type HealthCheckRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	unknownFields protoimpl.UnknownFields
@@ -333,7 +907,7 @@ type HealthCheckRequest struct {
 
 func (x *HealthCheckRequest) Reset() {
 	*x = HealthCheckRequest{}
-	mi := &file_service_proto_msgTypes[6]
+	mi := &file_service_proto_msgTypes[14]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -345,7 +919,7 @@ func (x *HealthCheckRequest) String() string {
 func (*HealthCheckRequest) ProtoMessage() {}
 
 func (x *HealthCheckRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_service_proto_msgTypes[6]
+	mi := &file_service_proto_msgTypes[14]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -358,7 +932,52 @@ func (x *HealthCheckRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HealthCheckRequest.ProtoReflect.Descriptor instead.
 func (*HealthCheckRequest) Descriptor() ([]byte, []int) {
-	return file_service_proto_rawDescGZIP(), []int{6}
+	return file_service_proto_rawDescGZIP(), []int{14}
+}
+
+type SlowCheckRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// How long to wait before answering; 0 uses the server's -slow-check-delay.
+	DelayMs       int32 `protobuf:"varint,1,opt,name=delay_ms,json=delayMs,proto3" json:"delay_ms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SlowCheckRequest) Reset() {
+	*x = SlowCheckRequest{}
+	mi := &file_service_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SlowCheckRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SlowCheckRequest) ProtoMessage() {}
+
+func (x *SlowCheckRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_service_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SlowCheckRequest.ProtoReflect.Descriptor instead.
+func (*SlowCheckRequest) Descriptor() ([]byte, []int) {
+	return file_service_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *SlowCheckRequest) GetDelayMs() int32 {
+	if x != nil {
+		return x.DelayMs
+	}
+	return 0
 }
 
 type HealthCheckResponse struct {
@@ -370,7 +989,7 @@ type HealthCheckResponse struct {
 
 func (x *HealthCheckResponse) Reset() {
 	*x = HealthCheckResponse{}
-	mi := &file_service_proto_msgTypes[7]
+	mi := &file_service_proto_msgTypes[16]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -382,7 +1001,7 @@ func (x *HealthCheckResponse) String() string {
 func (*HealthCheckResponse) ProtoMessage() {}
 
 func (x *HealthCheckResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_service_proto_msgTypes[7]
+	mi := &file_service_proto_msgTypes[16]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -395,7 +1014,7 @@ func (x *HealthCheckResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use HealthCheckResponse.ProtoReflect.Descriptor instead.
 func (*HealthCheckResponse) Descriptor() ([]byte, []int) {
-	return file_service_proto_rawDescGZIP(), []int{7}
+	return file_service_proto_rawDescGZIP(), []int{16}
 }
 
 func (x *HealthCheckResponse) GetStatus() string {
@@ -414,29 +1033,79 @@ const file_service_proto_rawDesc = "" +
 	"\amessage\x18\x01 \x01(\tR\amessage\"F\n" +
 	"\fEchoResponse\x12\x18\n" +
 	"\amessage\x18\x01 \x01(\tR\amessage\x12\x1c\n" +
-	"\ttimestamp\x18\x02 \x01(\x03R\ttimestamp\"@\n" +
+	"\ttimestamp\x18\x02 \x01(\x03R\ttimestamp\"/\n" +
+	"\x13EchoMetadataRequest\x12\x18\n" +
+	"\amessage\x18\x01 \x01(\tR\amessage\"9\n" +
+	"\rMetadataEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x16\n" +
+	"\x06values\x18\x02 \x03(\tR\x06values\"\xae\x02\n" +
+	"\x14EchoMetadataResponse\x12\x18\n" +
+	"\amessage\x18\x01 \x01(\tR\amessage\x12\x1c\n" +
+	"\ttimestamp\x18\x02 \x01(\x03R\ttimestamp\x12*\n" +
+	"\bmetadata\x18\x03 \x03(\v2\x0e.MetadataEntryR\bmetadata\x12\x1c\n" +
+	"\tauthority\x18\x04 \x01(\tR\tauthority\x12!\n" +
+	"\fhas_deadline\x18\x05 \x01(\bR\vhasDeadline\x12\x1a\n" +
+	"\bdeadline\x18\x06 \x01(\tR\bdeadline\x122\n" +
+	"\x15deadline_remaining_ms\x18\a \x01(\x03R\x13deadlineRemainingMs\x12!\n" +
+	"\fpeer_address\x18\b \x01(\tR\vpeerAddress\">\n" +
+	"\x0ePayloadRequest\x12\x12\n" +
+	"\x04size\x18\x01 \x01(\x03R\x04size\x12\x18\n" +
+	"\apayload\x18\x02 \x01(\fR\apayload\"d\n" +
+	"\x0fPayloadResponse\x12\x18\n" +
+	"\apayload\x18\x01 \x01(\fR\apayload\x12\x12\n" +
+	"\x04size\x18\x02 \x01(\x03R\x04size\x12#\n" +
+	"\rreceived_size\x18\x03 \x01(\x03R\freceivedSize\"8\n" +
+	"\fBurstRequest\x12\x14\n" +
+	"\x05count\x18\x01 \x01(\x05R\x05count\x12\x12\n" +
+	"\x04size\x18\x02 \x01(\x05R\x04size\"j\n" +
+	"\rBurstResponse\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x05R\x05index\x12\x18\n" +
+	"\apayload\x18\x02 \x01(\fR\apayload\x12)\n" +
+	"\x11sent_at_unix_nano\x18\x03 \x01(\x03R\x0esentAtUnixNano\"_\n" +
+	"\fErrorRequest\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\x05R\x04code\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\x12!\n" +
+	"\fwith_details\x18\x03 \x01(\bR\vwithDetails\"@\n" +
 	"\rStreamRequest\x12\x14\n" +
 	"\x05count\x18\x01 \x01(\x05R\x05count\x12\x19\n" +
-	"\bdelay_ms\x18\x02 \x01(\x05R\adelayMs\"^\n" +
+	"\bdelay_ms\x18\x02 \x01(\x05R\adelayMs\"\x9c\x02\n" +
 	"\x0eStreamResponse\x12\x14\n" +
 	"\x05index\x18\x01 \x01(\x05R\x05index\x12\x18\n" +
 	"\amessage\x18\x02 \x01(\tR\amessage\x12\x1c\n" +
-	"\ttimestamp\x18\x03 \x01(\x03R\ttimestamp\"/\n" +
+	"\ttimestamp\x18\x03 \x01(\x03R\ttimestamp\x12\x1a\n" +
+	"\bsequence\x18\x04 \x01(\x03R\bsequence\x12'\n" +
+	"\x0fclient_sequence\x18\x05 \x01(\x03R\x0eclientSequence\x121\n" +
+	"\x15received_at_unix_nano\x18\x06 \x01(\x03R\x12receivedAtUnixNano\x12)\n" +
+	"\x11sent_at_unix_nano\x18\a \x01(\x03R\x0esentAtUnixNano\x12\x19\n" +
+	"\bdelay_ms\x18\b \x01(\x05R\adelayMs\"f\n" +
 	"\x13ClientStreamRequest\x12\x18\n" +
-	"\amessage\x18\x01 \x01(\tR\amessage\"H\n" +
+	"\amessage\x18\x01 \x01(\tR\amessage\x12\x19\n" +
+	"\bdelay_ms\x18\x02 \x01(\x05R\adelayMs\x12\x1a\n" +
+	"\bsequence\x18\x03 \x01(\x03R\bsequence\"\xaa\x01\n" +
 	"\x14ClientStreamResponse\x12\x14\n" +
 	"\x05count\x18\x01 \x01(\x05R\x05count\x12\x1a\n" +
-	"\bmessages\x18\x02 \x03(\tR\bmessages\"\x14\n" +
+	"\bmessages\x18\x02 \x03(\tR\bmessages\x12\x1f\n" +
+	"\vtotal_bytes\x18\x03 \x01(\x03R\n" +
+	"totalBytes\x12'\n" +
+	"\x0fmessage_lengths\x18\x04 \x03(\x05R\x0emessageLengths\x12\x16\n" +
+	"\x06sha256\x18\x05 \x01(\tR\x06sha256\"\x14\n" +
 	"\x12HealthCheckRequest\"-\n" +
+	"\x10SlowCheckRequest\x12\x19\n" +
+	"\bdelay_ms\x18\x01 \x01(\x05R\adelayMs\"-\n" +
 	"\x13HealthCheckResponse\x12\x16\n" +
-	"\x06status\x18\x01 \x01(\tR\x06status2\xe6\x01\n" +
+	"\x06status\x18\x01 \x01(\tR\x06status2\xb3\x03\n" +
 	"\vEchoService\x12#\n" +
 	"\x04Echo\x12\f.EchoRequest\x1a\r.EchoResponse\x121\n" +
 	"\fServerStream\x12\x0e.StreamRequest\x1a\x0f.StreamResponse0\x01\x12=\n" +
 	"\fClientStream\x12\x14.ClientStreamRequest\x1a\x15.ClientStreamResponse(\x01\x12@\n" +
-	"\x13BidirectionalStream\x12\x14.ClientStreamRequest\x1a\x0f.StreamResponse(\x010\x012C\n" +
+	"\x13BidirectionalStream\x12\x14.ClientStreamRequest\x1a\x0f.StreamResponse(\x010\x01\x12;\n" +
+	"\fEchoMetadata\x12\x14.EchoMetadataRequest\x1a\x15.EchoMetadataResponse\x12+\n" +
+	"\vReturnError\x12\r.ErrorRequest\x1a\r.EchoResponse\x121\n" +
+	"\fLargePayload\x12\x0f.PayloadRequest\x1a\x10.PayloadResponse\x12.\n" +
+	"\vBurstStream\x12\r.BurstRequest\x1a\x0e.BurstResponse0\x012y\n" +
 	"\rHealthService\x122\n" +
-	"\x05Check\x12\x13.HealthCheckRequest\x1a\x14.HealthCheckResponseB\bZ\x06.;mainb\x06proto3"
+	"\x05Check\x12\x13.HealthCheckRequest\x1a\x14.HealthCheckResponse\x124\n" +
+	"\tSlowCheck\x12\x11.SlowCheckRequest\x1a\x14.HealthCheckResponseB\bZ\x06.;mainb\x06proto3"
 
 var (
 	file_service_proto_rawDescOnce sync.Once
@@ -450,33 +1119,53 @@ func file_service_proto_rawDescGZIP() []byte {
 	return file_service_proto_rawDescData
 }
 
-var file_service_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_service_proto_msgTypes = make([]protoimpl.MessageInfo, 17)
 var file_service_proto_goTypes = []any{
 	(*EchoRequest)(nil),          // 0: EchoRequest
 	(*EchoResponse)(nil),         // 1: EchoResponse
-	(*StreamRequest)(nil),        // 2: StreamRequest
-	(*StreamResponse)(nil),       // 3: StreamResponse
-	(*ClientStreamRequest)(nil),  // 4: ClientStreamRequest
-	(*ClientStreamResponse)(nil), // 5: ClientStreamResponse
-	(*HealthCheckRequest)(nil),   // 6: HealthCheckRequest
-	(*HealthCheckResponse)(nil),  // 7: HealthCheckResponse
+	(*EchoMetadataRequest)(nil),  // 2: EchoMetadataRequest
+	(*MetadataEntry)(nil),        // 3: MetadataEntry
+	(*EchoMetadataResponse)(nil), // 4: EchoMetadataResponse
+	(*PayloadRequest)(nil),       // 5: PayloadRequest
+	(*PayloadResponse)(nil),      // 6: PayloadResponse
+	(*BurstRequest)(nil),         // 7: BurstRequest
+	(*BurstResponse)(nil),        // 8: BurstResponse
+	(*ErrorRequest)(nil),         // 9: ErrorRequest
+	(*StreamRequest)(nil),        // 10: StreamRequest
+	(*StreamResponse)(nil),       // 11: StreamResponse
+	(*ClientStreamRequest)(nil),  // 12: ClientStreamRequest
+	(*ClientStreamResponse)(nil), // 13: ClientStreamResponse
+	(*HealthCheckRequest)(nil),   // 14: HealthCheckRequest
+	(*SlowCheckRequest)(nil),     // 15: SlowCheckRequest
+	(*HealthCheckResponse)(nil),  // 16: HealthCheckResponse
 }
 var file_service_proto_depIdxs = []int32{
-	0, // 0: EchoService.Echo:input_type -> EchoRequest
-	2, // 1: EchoService.ServerStream:input_type -> StreamRequest
-	4, // 2: EchoService.ClientStream:input_type -> ClientStreamRequest
-	4, // 3: EchoService.BidirectionalStream:input_type -> ClientStreamRequest
-	6, // 4: HealthService.Check:input_type -> HealthCheckRequest
-	1, // 5: EchoService.Echo:output_type -> EchoResponse
-	3, // 6: EchoService.ServerStream:output_type -> StreamResponse
-	5, // 7: EchoService.ClientStream:output_type -> ClientStreamResponse
-	3, // 8: EchoService.BidirectionalStream:output_type -> StreamResponse
-	7, // 9: HealthService.Check:output_type -> HealthCheckResponse
-	5, // [5:10] is the sub-list for method output_type
-	0, // [0:5] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	3,  // 0: EchoMetadataResponse.metadata:type_name -> MetadataEntry
+	0,  // 1: EchoService.Echo:input_type -> EchoRequest
+	10, // 2: EchoService.ServerStream:input_type -> StreamRequest
+	12, // 3: EchoService.ClientStream:input_type -> ClientStreamRequest
+	12, // 4: EchoService.BidirectionalStream:input_type -> ClientStreamRequest
+	2,  // 5: EchoService.EchoMetadata:input_type -> EchoMetadataRequest
+	9,  // 6: EchoService.ReturnError:input_type -> ErrorRequest
+	5,  // 7: EchoService.LargePayload:input_type -> PayloadRequest
+	7,  // 8: EchoService.BurstStream:input_type -> BurstRequest
+	14, // 9: HealthService.Check:input_type -> HealthCheckRequest
+	15, // 10: HealthService.SlowCheck:input_type -> SlowCheckRequest
+	1,  // 11: EchoService.Echo:output_type -> EchoResponse
+	11, // 12: EchoService.ServerStream:output_type -> StreamResponse
+	13, // 13: EchoService.ClientStream:output_type -> ClientStreamResponse
+	11, // 14: EchoService.BidirectionalStream:output_type -> StreamResponse
+	4,  // 15: EchoService.EchoMetadata:output_type -> EchoMetadataResponse
+	1,  // 16: EchoService.ReturnError:output_type -> EchoResponse
+	6,  // 17: EchoService.LargePayload:output_type -> PayloadResponse
+	8,  // 18: EchoService.BurstStream:output_type -> BurstResponse
+	16, // 19: HealthService.Check:output_type -> HealthCheckResponse
+	16, // 20: HealthService.SlowCheck:output_type -> HealthCheckResponse
+	11, // [11:21] is the sub-list for method output_type
+	1,  // [1:11] is the sub-list for method input_type
+	1,  // [1:1] is the sub-list for extension type_name
+	1,  // [1:1] is the sub-list for extension extendee
+	0,  // [0:1] is the sub-list for field type_name
 }
 
 func init() { file_service_proto_init() }
@@ -490,7 +1179,7 @@ func file_service_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_service_proto_rawDesc), len(file_service_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   8,
+			NumMessages:   17,
 			NumExtensions: 0,
 			NumServices:   2,
 		},
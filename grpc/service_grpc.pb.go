@@ -23,6 +23,10 @@ const (
 	EchoService_ServerStream_FullMethodName        = "/EchoService/ServerStream"
 	EchoService_ClientStream_FullMethodName        = "/EchoService/ClientStream"
 	EchoService_BidirectionalStream_FullMethodName = "/EchoService/BidirectionalStream"
+	EchoService_EchoMetadata_FullMethodName        = "/EchoService/EchoMetadata"
+	EchoService_ReturnError_FullMethodName         = "/EchoService/ReturnError"
+	EchoService_LargePayload_FullMethodName        = "/EchoService/LargePayload"
+	EchoService_BurstStream_FullMethodName         = "/EchoService/BurstStream"
 )
 
 // EchoServiceClient is the client API for EchoService service.
@@ -33,6 +37,10 @@ type EchoServiceClient interface {
 	ServerStream(ctx context.Context, in *StreamRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[StreamResponse], error)
 	ClientStream(ctx context.Context, opts ...grpc.CallOption) (grpc.ClientStreamingClient[ClientStreamRequest, ClientStreamResponse], error)
 	BidirectionalStream(ctx context.Context, opts ...grpc.CallOption) (grpc.BidiStreamingClient[ClientStreamRequest, StreamResponse], error)
+	EchoMetadata(ctx context.Context, in *EchoMetadataRequest, opts ...grpc.CallOption) (*EchoMetadataResponse, error)
+	ReturnError(ctx context.Context, in *ErrorRequest, opts ...grpc.CallOption) (*EchoResponse, error)
+	LargePayload(ctx context.Context, in *PayloadRequest, opts ...grpc.CallOption) (*PayloadResponse, error)
+	BurstStream(ctx context.Context, in *BurstRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[BurstResponse], error)
 }
 
 type echoServiceClient struct {
@@ -98,6 +106,55 @@ func (c *echoServiceClient) BidirectionalStream(ctx context.Context, opts ...grp
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type EchoService_BidirectionalStreamClient = grpc.BidiStreamingClient[ClientStreamRequest, StreamResponse]
 
+func (c *echoServiceClient) EchoMetadata(ctx context.Context, in *EchoMetadataRequest, opts ...grpc.CallOption) (*EchoMetadataResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EchoMetadataResponse)
+	err := c.cc.Invoke(ctx, EchoService_EchoMetadata_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *echoServiceClient) ReturnError(ctx context.Context, in *ErrorRequest, opts ...grpc.CallOption) (*EchoResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EchoResponse)
+	err := c.cc.Invoke(ctx, EchoService_ReturnError_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *echoServiceClient) LargePayload(ctx context.Context, in *PayloadRequest, opts ...grpc.CallOption) (*PayloadResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PayloadResponse)
+	err := c.cc.Invoke(ctx, EchoService_LargePayload_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *echoServiceClient) BurstStream(ctx context.Context, in *BurstRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[BurstResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &EchoService_ServiceDesc.Streams[3], EchoService_BurstStream_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[BurstRequest, BurstResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type EchoService_BurstStreamClient = grpc.ServerStreamingClient[BurstResponse]
+
 // EchoServiceServer is the server API for EchoService service.
 // All implementations must embed UnimplementedEchoServiceServer
 // for forward compatibility.
@@ -106,6 +163,10 @@ type EchoServiceServer interface {
 	ServerStream(*StreamRequest, grpc.ServerStreamingServer[StreamResponse]) error
 	ClientStream(grpc.ClientStreamingServer[ClientStreamRequest, ClientStreamResponse]) error
 	BidirectionalStream(grpc.BidiStreamingServer[ClientStreamRequest, StreamResponse]) error
+	EchoMetadata(context.Context, *EchoMetadataRequest) (*EchoMetadataResponse, error)
+	ReturnError(context.Context, *ErrorRequest) (*EchoResponse, error)
+	LargePayload(context.Context, *PayloadRequest) (*PayloadResponse, error)
+	BurstStream(*BurstRequest, grpc.ServerStreamingServer[BurstResponse]) error
 	mustEmbedUnimplementedEchoServiceServer()
 }
 
@@ -128,6 +189,18 @@ func (UnimplementedEchoServiceServer) ClientStream(grpc.ClientStreamingServer[Cl
 func (UnimplementedEchoServiceServer) BidirectionalStream(grpc.BidiStreamingServer[ClientStreamRequest, StreamResponse]) error {
 	return status.Error(codes.Unimplemented, "method BidirectionalStream not implemented")
 }
+func (UnimplementedEchoServiceServer) EchoMetadata(context.Context, *EchoMetadataRequest) (*EchoMetadataResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method EchoMetadata not implemented")
+}
+func (UnimplementedEchoServiceServer) ReturnError(context.Context, *ErrorRequest) (*EchoResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ReturnError not implemented")
+}
+func (UnimplementedEchoServiceServer) LargePayload(context.Context, *PayloadRequest) (*PayloadResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method LargePayload not implemented")
+}
+func (UnimplementedEchoServiceServer) BurstStream(*BurstRequest, grpc.ServerStreamingServer[BurstResponse]) error {
+	return status.Error(codes.Unimplemented, "method BurstStream not implemented")
+}
 func (UnimplementedEchoServiceServer) mustEmbedUnimplementedEchoServiceServer() {}
 func (UnimplementedEchoServiceServer) testEmbeddedByValue()                     {}
 
@@ -192,6 +265,71 @@ func _EchoService_BidirectionalStream_Handler(srv interface{}, stream grpc.Serve
 // This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
 type EchoService_BidirectionalStreamServer = grpc.BidiStreamingServer[ClientStreamRequest, StreamResponse]
 
+func _EchoService_EchoMetadata_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EchoMetadataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EchoServiceServer).EchoMetadata(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EchoService_EchoMetadata_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EchoServiceServer).EchoMetadata(ctx, req.(*EchoMetadataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EchoService_ReturnError_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ErrorRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EchoServiceServer).ReturnError(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EchoService_ReturnError_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EchoServiceServer).ReturnError(ctx, req.(*ErrorRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EchoService_LargePayload_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PayloadRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(EchoServiceServer).LargePayload(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: EchoService_LargePayload_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(EchoServiceServer).LargePayload(ctx, req.(*PayloadRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _EchoService_BurstStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(BurstRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(EchoServiceServer).BurstStream(m, &grpc.GenericServerStream[BurstRequest, BurstResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type EchoService_BurstStreamServer = grpc.ServerStreamingServer[BurstResponse]
+
 // EchoService_ServiceDesc is the grpc.ServiceDesc for EchoService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -203,6 +341,18 @@ var EchoService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Echo",
 			Handler:    _EchoService_Echo_Handler,
 		},
+		{
+			MethodName: "EchoMetadata",
+			Handler:    _EchoService_EchoMetadata_Handler,
+		},
+		{
+			MethodName: "ReturnError",
+			Handler:    _EchoService_ReturnError_Handler,
+		},
+		{
+			MethodName: "LargePayload",
+			Handler:    _EchoService_LargePayload_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -221,12 +371,18 @@ var EchoService_ServiceDesc = grpc.ServiceDesc{
 			ServerStreams: true,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "BurstStream",
+			Handler:       _EchoService_BurstStream_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "service.proto",
 }
 
 const (
-	HealthService_Check_FullMethodName = "/HealthService/Check"
+	HealthService_Check_FullMethodName     = "/HealthService/Check"
+	HealthService_SlowCheck_FullMethodName = "/HealthService/SlowCheck"
 )
 
 // HealthServiceClient is the client API for HealthService service.
@@ -234,6 +390,7 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type HealthServiceClient interface {
 	Check(ctx context.Context, in *HealthCheckRequest, opts ...grpc.CallOption) (*HealthCheckResponse, error)
+	SlowCheck(ctx context.Context, in *SlowCheckRequest, opts ...grpc.CallOption) (*HealthCheckResponse, error)
 }
 
 type healthServiceClient struct {
@@ -254,11 +411,22 @@ func (c *healthServiceClient) Check(ctx context.Context, in *HealthCheckRequest,
 	return out, nil
 }
 
+func (c *healthServiceClient) SlowCheck(ctx context.Context, in *SlowCheckRequest, opts ...grpc.CallOption) (*HealthCheckResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HealthCheckResponse)
+	err := c.cc.Invoke(ctx, HealthService_SlowCheck_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // HealthServiceServer is the server API for HealthService service.
 // All implementations must embed UnimplementedHealthServiceServer
 // for forward compatibility.
 type HealthServiceServer interface {
 	Check(context.Context, *HealthCheckRequest) (*HealthCheckResponse, error)
+	SlowCheck(context.Context, *SlowCheckRequest) (*HealthCheckResponse, error)
 	mustEmbedUnimplementedHealthServiceServer()
 }
 
@@ -272,6 +440,9 @@ type UnimplementedHealthServiceServer struct{}
 func (UnimplementedHealthServiceServer) Check(context.Context, *HealthCheckRequest) (*HealthCheckResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method Check not implemented")
 }
+func (UnimplementedHealthServiceServer) SlowCheck(context.Context, *SlowCheckRequest) (*HealthCheckResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method SlowCheck not implemented")
+}
 func (UnimplementedHealthServiceServer) mustEmbedUnimplementedHealthServiceServer() {}
 func (UnimplementedHealthServiceServer) testEmbeddedByValue()                       {}
 
@@ -311,6 +482,24 @@ func _HealthService_Check_Handler(srv interface{}, ctx context.Context, dec func
 	return interceptor(ctx, in, info, handler)
 }
 
+func _HealthService_SlowCheck_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SlowCheckRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(HealthServiceServer).SlowCheck(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: HealthService_SlowCheck_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(HealthServiceServer).SlowCheck(ctx, req.(*SlowCheckRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // HealthService_ServiceDesc is the grpc.ServiceDesc for HealthService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -322,6 +511,10 @@ var HealthService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Check",
 			Handler:    _HealthService_Check_Handler,
 		},
+		{
+			MethodName: "SlowCheck",
+			Handler:    _HealthService_SlowCheck_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "service.proto",
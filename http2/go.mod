@@ -2,6 +2,18 @@ module http2
 
 go 1.21
 
-require golang.org/x/net v0.21.0
+require (
+	github.com/prometheus/client_golang v1.19.1
+	golang.org/x/net v0.30.0
+)
 
-require golang.org/x/text v0.14.0 // indirect
+require (
+	github.com/beorn7/perks v1.0.1 // indirect
+	github.com/cespare/xxhash/v2 v2.2.0 // indirect
+	github.com/prometheus/client_model v0.5.0 // indirect
+	github.com/prometheus/common v0.48.0 // indirect
+	github.com/prometheus/procfs v0.12.0 // indirect
+	golang.org/x/sys v0.26.0 // indirect
+	golang.org/x/text v0.19.0 // indirect
+	google.golang.org/protobuf v1.33.0 // indirect
+)
@@ -1,87 +1,539 @@
 package main
 
 import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
 	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"log/slog"
+	"math"
+	mrand "math/rand"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/net/http/httpguts"
 	"golang.org/x/net/http2"
 	"golang.org/x/net/http2/h2c"
 )
 
+// infoSequence orders /info requests across concurrent streams. Per-connection
+// stream counts and push origin aren't exposed by the HTTP/2 server, so this
+// and the start timestamp are the correlation points clients get.
+var infoSequence atomic.Uint64
+
+type requestIDKey struct{}
+
+type connInfoKey struct{}
+
+// connInfo identifies an accepted TCP/TLS connection. It is attached via
+// http.Server.ConnContext, which also reaches HTTP/2 streams on that conn.
+type connInfo struct {
+	id       string
+	requests atomic.Int64
+}
+
+func connInfoFrom(r *http.Request) *connInfo {
+	ci, _ := r.Context().Value(connInfoKey{}).(*connInfo)
+	return ci
+}
+
+// withConnRequests counts the requests served on each connection.
+func withConnRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ci := connInfoFrom(r); ci != nil {
+			ci.requests.Add(1)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// withH2CConns counts connections taken over by the h2c handler, which
+// serves them inside ServeHTTP until they close. They are hijacked, so
+// ConnState stops tracking them and http.Server.Shutdown doesn't wait for
+// them; main waits on conns instead.
+func withH2CConns(next http.Handler, conns *atomic.Int64) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		priorKnowledge := r.Method == "PRI" && r.URL.Path == "*" && r.Proto == "HTTP/2.0"
+		upgrade := httpguts.HeaderValuesContainsToken(r.Header["Upgrade"], "h2c") &&
+			httpguts.HeaderValuesContainsToken(r.Header["Connection"], "HTTP2-Settings")
+		if priorKnowledge || upgrade {
+			conns.Add(1)
+			defer conns.Add(-1)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// waitDrained polls until conns drops to zero or ctx is done.
+func waitDrained(ctx context.Context, conns *atomic.Int64) error {
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for conns.Load() > 0 {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// newRequestID returns a random (version 4) UUID.
+func newRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 36)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// withRequestID propagates X-Request-ID, generating one when the client (or
+// proxy) didn't send it, and echoes it back on the response.
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get("X-Request-ID")
+		if id == "" || len(id) > 128 {
+			id = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+func requestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDKey{}).(string)
+	return id
+}
+
+// jsonLogs switches logf and the access log to structured slog output.
+var jsonLogs bool
+
+// logf logs with the request ID as a prefix (or field, in JSON mode) so
+// origin logs can be matched against the proxy's.
+func logf(r *http.Request, format string, args ...interface{}) {
+	if jsonLogs {
+		slog.Info(fmt.Sprintf(format, args...), "request_id", requestID(r))
+		return
+	}
+	log.Printf("[%s] "+format, append([]interface{}{requestID(r)}, args...)...)
+}
+
+// statusRecorder captures the final status code and body size for the access
+// log while still exposing Flush to streaming handlers.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (sr *statusRecorder) WriteHeader(code int) {
+	if sr.status == 0 && code >= 200 {
+		sr.status = code
+	}
+	sr.ResponseWriter.WriteHeader(code)
+}
+
+func (sr *statusRecorder) Write(b []byte) (int, error) {
+	if sr.status == 0 {
+		sr.status = http.StatusOK
+	}
+	n, err := sr.ResponseWriter.Write(b)
+	sr.bytes += int64(n)
+	return n, err
+}
+
+func (sr *statusRecorder) Flush() {
+	if f, ok := sr.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (sr *statusRecorder) Unwrap() http.ResponseWriter {
+	return sr.ResponseWriter
+}
+
+// pushingStatusRecorder is used when the underlying writer supports server
+// push, so handlers can keep detecting push support with a type assertion.
+type pushingStatusRecorder struct {
+	*statusRecorder
+}
+
+func (pr pushingStatusRecorder) Push(target string, opts *http.PushOptions) error {
+	return pr.ResponseWriter.(http.Pusher).Push(target, opts)
+}
+
+func withAccessLog(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sr := &statusRecorder{ResponseWriter: w}
+		var rw http.ResponseWriter = sr
+		if _, ok := w.(http.Pusher); ok {
+			rw = pushingStatusRecorder{sr}
+		}
+
+		next.ServeHTTP(rw, r)
+
+		duration := time.Since(start)
+		if jsonLogs {
+			slog.Info("request",
+				"proto", r.Proto,
+				"method", r.Method,
+				"path", r.URL.Path,
+				"status", sr.status,
+				"bytes", sr.bytes,
+				"duration_ms", float64(duration.Microseconds())/1000,
+				"request_id", requestID(r),
+			)
+			return
+		}
+		logf(r, "%s %s %s %d %dB %s", r.Method, r.URL.Path, r.Proto, sr.status, sr.bytes, duration.Round(time.Microsecond))
+	})
+}
+
+var (
+	requestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "http2_eval_requests_total",
+		Help: "Requests served, by handler pattern and protocol.",
+	}, []string{"handler", "protocol"})
+	requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "http2_eval_request_duration_seconds",
+		Help:    "Time from request start until the handler returned.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"handler", "protocol"})
+	inflightStreams = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "http2_eval_inflight_streams",
+		Help: "Requests (HTTP/2 streams or HTTP/1.1 exchanges) currently being handled.",
+	}, []string{"protocol"})
+)
+
+// withMetrics records request counts, durations and in-flight streams. The
+// handler label is the mux pattern so it stays low-cardinality.
+func withMetrics(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := mux.Handler(r)
+		start := time.Now()
+
+		inflight := inflightStreams.WithLabelValues(r.Proto)
+		inflight.Inc()
+		defer func() {
+			inflight.Dec()
+			requestsTotal.WithLabelValues(pattern, r.Proto).Inc()
+			requestDuration.WithLabelValues(pattern, r.Proto).Observe(time.Since(start).Seconds())
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+type infoResponse struct {
+	Protocol   string      `json:"protocol"`
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	Host       string      `json:"host"`
+	RemoteAddr string      `json:"remote_addr"`
+	TLS        string      `json:"tls"`
+	Headers    http.Header `json:"headers"`
+
+	RequestStartNanos int64  `json:"request_start_nanos"`
+	RequestSequence   uint64 `json:"request_sequence"`
+
+	// Two requests sharing a backend connection report the same ID.
+	ConnectionID       string `json:"connection_id"`
+	ConnectionRequests int64  `json:"connection_requests"`
+
+	// Populated only when the request arrived over TLS.
+	TLSVersionName             string `json:"tls_version_name,omitempty"`
+	ALPN                       string `json:"alpn,omitempty"`
+	SNI                        string `json:"sni,omitempty"`
+	NegotiatedProtocolIsMutual *bool  `json:"negotiated_protocol_is_mutual,omitempty"`
+
+	// Null unless the client presented a certificate (see -client-auth).
+	ClientCert *clientCertInfo `json:"client_cert"`
+}
+
+type clientCertInfo struct {
+	Subject        string   `json:"subject"`
+	Issuer         string   `json:"issuer"`
+	SerialNumber   string   `json:"serial_number"`
+	NotAfter       string   `json:"not_after"`
+	DNSNames       []string `json:"dns_names,omitempty"`
+	IPAddresses    []string `json:"ip_addresses,omitempty"`
+	EmailAddresses []string `json:"email_addresses,omitempty"`
+	URIs           []string `json:"uris,omitempty"`
+}
+
+func newClientCertInfo(cert *x509.Certificate) *clientCertInfo {
+	info := &clientCertInfo{
+		Subject:        cert.Subject.String(),
+		Issuer:         cert.Issuer.String(),
+		SerialNumber:   cert.SerialNumber.String(),
+		NotAfter:       cert.NotAfter.UTC().Format(time.RFC3339),
+		DNSNames:       cert.DNSNames,
+		EmailAddresses: cert.EmailAddresses,
+	}
+	for _, ip := range cert.IPAddresses {
+		info.IPAddresses = append(info.IPAddresses, ip.String())
+	}
+	for _, u := range cert.URIs {
+		info.URIs = append(info.URIs, u.String())
+	}
+	return info
+}
+
+// applyHeaderParams sets response headers requested via repeated
+// ?header=Name:Value parameters. Values containing CR or LF are rejected
+// rather than sanitized so a test never silently sends something else.
+func applyHeaderParams(w http.ResponseWriter, r *http.Request) error {
+	for _, param := range r.URL.Query()["header"] {
+		name, value, ok := strings.Cut(param, ":")
+		name = strings.TrimSpace(name)
+		value = strings.TrimSpace(value)
+		if !ok || !httpguts.ValidHeaderFieldName(name) {
+			return fmt.Errorf("invalid header parameter %q: expected Name:Value", param)
+		}
+		if strings.ContainsAny(value, "\r\n") || !httpguts.ValidHeaderFieldValue(value) {
+			return fmt.Errorf("invalid value for header %q", name)
+		}
+		w.Header().Add(name, value)
+	}
+	return nil
+}
+
 func handleInfo(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	if err := applyHeaderParams(w, r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	w.Header().Set("Content-Type", "application/json")
 
-	proto := r.Proto
-	tlsInfo := "none"
+	info := infoResponse{
+		Protocol:          r.Proto,
+		Method:            r.Method,
+		URL:               r.URL.String(),
+		Host:              r.Host,
+		RemoteAddr:        r.RemoteAddr,
+		TLS:               "none",
+		Headers:           r.Header,
+		RequestStartNanos: start.UnixNano(),
+		RequestSequence:   infoSequence.Add(1),
+	}
+
+	if ci := connInfoFrom(r); ci != nil {
+		info.ConnectionID = ci.id
+		info.ConnectionRequests = ci.requests.Load()
+	}
+
 	if r.TLS != nil {
-		tlsInfo = fmt.Sprintf("version=%d, cipher=%d", r.TLS.Version, r.TLS.CipherSuite)
+		info.TLS = fmt.Sprintf("version=%d, cipher=%d", r.TLS.Version, r.TLS.CipherSuite)
+		info.TLSVersionName = tls.VersionName(r.TLS.Version)
+		info.ALPN = r.TLS.NegotiatedProtocol
+		info.SNI = r.TLS.ServerName
+		info.NegotiatedProtocolIsMutual = &r.TLS.NegotiatedProtocolIsMutual
+		if len(r.TLS.PeerCertificates) > 0 {
+			info.ClientCert = newClientCertInfo(r.TLS.PeerCertificates[0])
+		}
 	}
 
-	json := fmt.Sprintf(`{
-  "protocol": %q,
-  "method": %q,
-  "url": %q,
-  "host": %q,
-  "remote_addr": %q,
-  "tls": %q,
-  "headers": {`, proto, r.Method, r.URL.String(), r.Host, r.RemoteAddr, tlsInfo)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(info); err != nil {
+		logf(r, "Info encode error: %v", err)
+		return
+	}
+	logf(r, "Info request: proto=%s, method=%s, url=%s", r.Proto, r.Method, r.URL.String())
+}
+
+const maxPushCount = 50
 
-	first := true
-	for k, v := range r.Header {
-		if !first {
-			json += ","
+type pushResult struct {
+	Path   string `json:"path"`
+	Pushed bool   `json:"pushed"`
+	Error  string `json:"error,omitempty"`
+}
+
+type pushResponse struct {
+	PushSupported bool         `json:"push_supported"`
+	Pushed        []string     `json:"pushed"`
+	Results       []pushResult `json:"results"`
+}
+
+func pushResources(r *http.Request) []string {
+	if paths := r.URL.Query().Get("paths"); paths != "" {
+		resources := []string{}
+		for _, p := range strings.Split(paths, ",") {
+			if p = strings.TrimSpace(p); p != "" {
+				resources = append(resources, p)
+			}
 		}
-		json += fmt.Sprintf("\n    %q: %q", k, strings.Join(v, ", "))
-		first = false
+		return resources
 	}
-	json += "\n  }\n}"
 
-	w.Write([]byte(json))
-	log.Printf("Info request: proto=%s, method=%s, url=%s", proto, r.Method, r.URL.String())
+	if countStr := r.URL.Query().Get("count"); countStr != "" {
+		if c, err := strconv.Atoi(countStr); err == nil && c > 0 {
+			if c > maxPushCount {
+				c = maxPushCount
+			}
+			resources := make([]string, c)
+			for i := range resources {
+				resources[i] = fmt.Sprintf("/pushed-resource-%d", i+1)
+			}
+			return resources
+		}
+	}
+
+	return []string{"/pushed-resource-1", "/pushed-resource-2", "/pushed-resource-3"}
 }
 
-func handlePush(w http.ResponseWriter, r *http.Request) {
+// isRegistered reports whether path is served by something other than the
+// catch-all client page.
+func isRegistered(mux *http.ServeMux, path string) bool {
+	req, err := http.NewRequest(http.MethodGet, path, nil)
+	if err != nil {
+		return false
+	}
+	_, pattern := mux.Handler(req)
+	return pattern != "" && pattern != "/"
+}
+
+func handlePush(mux *http.ServeMux, w http.ResponseWriter, r *http.Request) {
 	pusher, ok := w.(http.Pusher)
 	if !ok {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte(`{"push_supported": false, "message": "Server push not available (HTTP/1.1 or push disabled)"}`))
-		log.Printf("Push not supported for %s", r.Proto)
+		logf(r, "Push not supported for %s", r.Proto)
 		return
 	}
 
-	resources := []string{"/pushed-resource-1", "/pushed-resource-2", "/pushed-resource-3"}
-
-	pushed := []string{}
-	for _, res := range resources {
-		err := pusher.Push(res, nil)
-		if err != nil {
-			log.Printf("Push failed for %s: %v", res, err)
+	resp := pushResponse{
+		PushSupported: true,
+		Pushed:        []string{},
+		Results:       []pushResult{},
+	}
+	for _, res := range pushResources(r) {
+		result := pushResult{Path: res}
+		if !isRegistered(mux, res) {
+			result.Error = "no handler registered for path"
+		} else if err := pusher.Push(res, nil); err != nil {
+			logf(r, "Push failed for %s: %v", res, err)
+			result.Error = err.Error()
 		} else {
-			pushed = append(pushed, res)
+			result.Pushed = true
+			resp.Pushed = append(resp.Pushed, res)
 		}
+		resp.Results = append(resp.Results, result)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	json := fmt.Sprintf(`{"push_supported": true, "pushed": ["%s"]}`, strings.Join(pushed, `", "`))
-	w.Write([]byte(json))
-	log.Printf("Pushed %d resources", len(pushed))
+	json.NewEncoder(w).Encode(resp)
+	logf(r, "Pushed %d of %d resources", len(resp.Pushed), len(resp.Results))
+}
+
+type pushNode struct {
+	Path     string     `json:"path"`
+	Pushed   bool       `json:"pushed"`
+	Error    string     `json:"error,omitempty"`
+	Children []pushNode `json:"children,omitempty"`
+}
+
+// nestedPushes maps a /push-nested request's token to the channel its pushed
+// child reports back on.
+var nestedPushes sync.Map
+
+// handlePushNested pushes /pushed-resource-1, whose handler in turn tries to
+// push /pushed-resource-2, and reports the resulting tree. Go's HTTP/2 server
+// refuses pushes from pushed streams, so the nested push is expected to fail
+// with a recursive-push error; clients that disabled push fail one level up.
+func handlePushNested(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	pusher, ok := w.(http.Pusher)
+	if !ok {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"push_supported": false, "message": "Server push not available (HTTP/1.1 or push disabled)"}`))
+		logf(r, "Nested push not supported for %s", r.Proto)
+		return
+	}
+
+	token := newRequestID()
+	report := make(chan pushNode, 1)
+	nestedPushes.Store(token, report)
+	defer nestedPushes.Delete(token)
+
+	child := pushNode{Path: "/pushed-resource-1"}
+	if err := pusher.Push(child.Path+"?nested="+token, nil); err != nil {
+		child.Error = err.Error()
+	} else {
+		child.Pushed = true
+		select {
+		case grandchild := <-report:
+			child.Children = []pushNode{grandchild}
+		case <-time.After(2 * time.Second):
+			child.Error = "pushed handler did not report (stream reset by client?)"
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"push_supported": true,
+		"path":           r.URL.Path,
+		"pushes":         []pushNode{child},
+	})
+	logf(r, "Nested push: child pushed=%t, error=%q", child.Pushed, child.Error)
 }
 
 func handlePushedResource(w http.ResponseWriter, r *http.Request) {
+	if token := r.URL.Query().Get("nested"); token != "" {
+		if v, ok := nestedPushes.Load(token); ok {
+			node := pushNode{Path: "/pushed-resource-2"}
+			if pusher, ok := w.(http.Pusher); !ok {
+				node.Error = "push not supported on this stream"
+			} else if err := pusher.Push(node.Path, nil); err != nil {
+				node.Error = err.Error()
+			} else {
+				node.Pushed = true
+			}
+			select {
+			case v.(chan pushNode) <- node:
+			default:
+			}
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.Header().Set("Cache-Control", "max-age=3600")
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(fmt.Sprintf(`{"resource": %q, "timestamp": %q}`, r.URL.Path, time.Now().Format(time.RFC3339))))
 }
 
+const maxMultiplexJitter = 5000
+
 func handleMultiplex(w http.ResponseWriter, r *http.Request) {
 	flusher, ok := w.(http.Flusher)
 	if !ok {
@@ -105,19 +557,50 @@ func handleMultiplex(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	jitterStr := r.URL.Query().Get("jitter")
+	jitter := 0
+	if jitterStr != "" {
+		if j, err := strconv.Atoi(jitterStr); err == nil && j > 0 {
+			jitter = j
+			if jitter > maxMultiplexJitter {
+				jitter = maxMultiplexJitter
+			}
+		}
+	}
+	rng := mrand.New(mrand.NewSource(time.Now().UnixNano()))
+
 	w.Header().Set("Content-Type", "text/plain")
 
-	log.Printf("Multiplex test: count=%d, delay=%dms, proto=%s", count, delay, r.Proto)
+	logf(r, "Multiplex test: count=%d, delay=%dms, jitter=%dms, proto=%s", count, delay, jitter, r.Proto)
 
 	for i := 1; i <= count; i++ {
-		msg := fmt.Sprintf("Message %d/%d at %s (proto: %s)\n", i, count, time.Now().Format(time.RFC3339Nano), r.Proto)
-		w.Write([]byte(msg))
-		flusher.Flush()
+		// wait is the pause that preceded this message, so each line reports
+		// the delay that was actually applied.
+		wait := 0
+		if i > 1 {
+			wait = delay
+			if jitter > 0 {
+				wait += rng.Intn(jitter + 1)
+			}
+		}
+		if wait > 0 {
+			select {
+			case <-r.Context().Done():
+				logf(r, "Multiplex client gone after %d/%d messages: %v", i-1, count, r.Context().Err())
+				return
+			case <-time.After(time.Duration(wait) * time.Millisecond):
+			}
+		}
 
-		if i < count && delay > 0 {
-			time.Sleep(time.Duration(delay) * time.Millisecond)
+		msg := fmt.Sprintf("Message %d/%d at %s (proto: %s, delay: %dms)\n", i, count, time.Now().Format(time.RFC3339Nano), r.Proto, wait)
+		if _, err := w.Write([]byte(msg)); err != nil {
+			logf(r, "Multiplex write error after %d/%d messages: %v", i-1, count, err)
+			return
 		}
+		flusher.Flush()
 	}
+
+	logf(r, "Multiplex complete: delivered %d messages", count)
 }
 
 func handleConcurrent(w http.ResponseWriter, r *http.Request) {
@@ -138,12 +621,634 @@ func handleConcurrent(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(json))
 }
 
+func handleStatus(w http.ResponseWriter, r *http.Request) {
+	codeStr := strings.TrimPrefix(r.URL.Path, "/status/")
+	code, err := strconv.Atoi(codeStr)
+	if err != nil || code < 100 || code > 599 {
+		http.Error(w, "Status code must be an integer between 100 and 599", http.StatusBadRequest)
+		return
+	}
+	// 101 would hand the connection over to another protocol, which neither
+	// net/http nor HTTP/2 can do from a plain handler.
+	if code == http.StatusSwitchingProtocols {
+		http.Error(w, "Status 101 is not supported", http.StatusBadRequest)
+		return
+	}
+
+	logf(r, "Status request: code=%d, proto=%s", code, r.Proto)
+
+	if code < 200 {
+		// Informational responses are sent as interim headers, followed by a
+		// normal final response so the client can observe both.
+		w.WriteHeader(code)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(fmt.Sprintf(`{"status":%d,"informational":%d}`, http.StatusOK, code)))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if code == http.StatusNoContent || code == http.StatusNotModified {
+		return
+	}
+	w.Write([]byte(fmt.Sprintf(`{"status":%d}`, code)))
+}
+
+func handleDownload(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	sizeStr := r.URL.Query().Get("size")
+	size := 1024 * 1024
+	if sizeStr != "" {
+		if s, err := strconv.Atoi(sizeStr); err == nil && s > 0 {
+			size = s
+		}
+	}
+
+	// fail-at aborts the stream after that many bytes so proxies can be
+	// observed handling a truncated response.
+	failAt := -1
+	if v := r.URL.Query().Get("fail-at"); v != "" {
+		if f, err := strconv.Atoi(v); err == nil && f >= 0 && f < size {
+			failAt = f
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.Header().Set("Content-Length", strconv.Itoa(size))
+
+	logf(r, "Download: size=%d, fail-at=%d, proto=%s", size, failAt, r.Proto)
+
+	// Predictable text lines keep the body both verifiable and compressible.
+	line := []byte("The quick brown fox jumps over the lazy dog 0123456789\n")
+	buf := make([]byte, 0, 32*1024)
+	for len(buf)+len(line) <= cap(buf) {
+		buf = append(buf, line...)
+	}
+
+	sent := 0
+	for sent < size {
+		toSend := len(buf)
+		if remaining := size - sent; remaining < toSend {
+			toSend = remaining
+		}
+		if failAt >= 0 && failAt-sent < toSend {
+			toSend = failAt - sent
+		}
+		n, err := w.Write(buf[:toSend])
+		if err != nil {
+			logf(r, "Download write error after %d bytes: %v", sent, err)
+			return
+		}
+		sent += n
+		flusher.Flush()
+
+		if sent == failAt {
+			logf(r, "Download aborted at %d of %d bytes (fail-at)", sent, size)
+			panic(http.ErrAbortHandler)
+		}
+	}
+
+	logf(r, "Download complete: sent %d bytes", sent)
+}
+
+// handleDrip writes a fixed number of bytes at a target rate, flushing after
+// every write, to expose proxy buffering and backpressure.
+func handleDrip(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	bytesStr := r.URL.Query().Get("bytes")
+	total := 1024
+	if bytesStr != "" {
+		if b, err := strconv.Atoi(bytesStr); err == nil && b > 0 {
+			total = b
+		}
+	}
+
+	rateStr := r.URL.Query().Get("rate")
+	rate := 128
+	if rateStr != "" {
+		if b, err := strconv.Atoi(rateStr); err == nil && b > 0 {
+			rate = b
+		}
+	}
+
+	// Write ten times a second, or one byte at a time for very low rates.
+	chunkSize := rate / 10
+	interval := 100 * time.Millisecond
+	if chunkSize < 1 {
+		chunkSize = 1
+		interval = time.Second / time.Duration(rate)
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(total))
+	w.Header().Set("X-Drip-Rate", strconv.Itoa(rate))
+	w.WriteHeader(http.StatusOK)
+
+	logf(r, "Drip: bytes=%d, rate=%dB/s, chunk=%d, interval=%s, proto=%s", total, rate, chunkSize, interval, r.Proto)
+
+	chunk := bytes.Repeat([]byte("."), chunkSize)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	start := time.Now()
+	sent := 0
+	for sent < total {
+		toSend := chunkSize
+		if remaining := total - sent; remaining < toSend {
+			toSend = remaining
+		}
+		n, err := w.Write(chunk[:toSend])
+		sent += n
+		if err != nil {
+			logf(r, "Drip write error after %d bytes: %v", sent, err)
+			return
+		}
+		flusher.Flush()
+
+		if sent < total {
+			select {
+			case <-r.Context().Done():
+				logf(r, "Drip cancelled after %d bytes", sent)
+				return
+			case <-ticker.C:
+			}
+		}
+	}
+
+	elapsed := time.Since(start)
+	logf(r, "Drip complete: %d bytes in %s (%.1f B/s, target %d B/s)", sent, elapsed, float64(sent)/elapsed.Seconds(), rate)
+}
+
+// compressor is implemented by both gzip.Writer and zlib.Writer. HTTP's
+// "deflate" coding is the zlib format, not raw DEFLATE.
+type compressor interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// compressWriter compresses the response body once the handler commits to a
+// compressible Content-Type. Flush pushes buffered compressed data through so
+// streaming endpoints keep working.
+type compressWriter struct {
+	http.ResponseWriter
+	encoding    string
+	enc         compressor
+	wroteHeader bool
+}
+
+func (cw *compressWriter) WriteHeader(code int) {
+	if cw.wroteHeader {
+		return
+	}
+	if code < 200 {
+		cw.ResponseWriter.WriteHeader(code)
+		return
+	}
+	cw.wroteHeader = true
+
+	h := cw.Header()
+	if code != http.StatusNoContent && code != http.StatusNotModified &&
+		h.Get("Content-Encoding") == "" && isCompressible(h.Get("Content-Type")) {
+		h.Set("Content-Encoding", cw.encoding)
+		h.Del("Content-Length")
+		if cw.encoding == "gzip" {
+			cw.enc = gzip.NewWriter(cw.ResponseWriter)
+		} else {
+			cw.enc = zlib.NewWriter(cw.ResponseWriter)
+		}
+	}
+	cw.ResponseWriter.WriteHeader(code)
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.wroteHeader {
+		if cw.Header().Get("Content-Type") == "" {
+			cw.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		cw.WriteHeader(http.StatusOK)
+	}
+	if cw.enc != nil {
+		return cw.enc.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+func (cw *compressWriter) Flush() {
+	if cw.enc != nil {
+		cw.enc.Flush()
+	}
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+func (cw *compressWriter) Close() error {
+	if cw.enc != nil {
+		return cw.enc.Close()
+	}
+	return nil
+}
+
+func isCompressible(contentType string) bool {
+	ct := strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0])
+	switch {
+	case strings.HasPrefix(ct, "text/"):
+		return true
+	case ct == "application/json", ct == "application/javascript", ct == "application/xml", ct == "image/svg+xml":
+		return true
+	}
+	return false
+}
+
+// negotiateEncoding picks gzip or deflate from Accept-Encoding, preferring
+// gzip. Codings explicitly refused with q=0 are skipped.
+func negotiateEncoding(acceptEncoding string) string {
+	accepted := map[string]bool{}
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(fields[0]))
+		refused := false
+		for _, param := range fields[1:] {
+			param = strings.ReplaceAll(param, " ", "")
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil && q == 0 {
+					refused = true
+				}
+			}
+		}
+		if !refused {
+			accepted[coding] = true
+		}
+	}
+
+	switch {
+	case accepted["gzip"]:
+		return "gzip"
+	case accepted["deflate"]:
+		return "deflate"
+	}
+	return ""
+}
+
+func withCompression(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Method == http.MethodHead {
+			next(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding}
+		defer cw.Close()
+		next(cw, r)
+	}
+}
+
+// handleTrailers streams rows of text and reports their SHA-256 and count as
+// trailers. Over HTTP/2 trailers go out in a final HEADERS frame. Over
+// HTTP/1.1 they are only legal with chunked encoding: net/http switches to
+// chunked because no Content-Length is set and sends them after the last
+// chunk. HTTP/1.0 clients get neither chunking nor trailers.
+func handleTrailers(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	rowsStr := r.URL.Query().Get("rows")
+	rows := 10
+	if rowsStr != "" {
+		if n, err := strconv.Atoi(rowsStr); err == nil && n > 0 && n <= 10000 {
+			rows = n
+		}
+	}
+
+	delayStr := r.URL.Query().Get("delay")
+	delay := 100
+	if delayStr != "" {
+		if d, err := strconv.Atoi(delayStr); err == nil && d >= 0 {
+			delay = d
+		}
+	}
+
+	w.Header().Set("Trailer", "X-Checksum, X-Row-Count")
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+
+	logf(r, "Trailers test: rows=%d, delay=%dms, proto=%s", rows, delay, r.Proto)
+
+	hash := sha256.New()
+	sent := 0
+	for i := 1; i <= rows; i++ {
+		row := fmt.Sprintf("Row %d/%d at %s\n", i, rows, time.Now().Format(time.RFC3339Nano))
+		if _, err := io.WriteString(io.MultiWriter(w, hash), row); err != nil {
+			logf(r, "Trailers write error after %d rows: %v", sent, err)
+			return
+		}
+		sent++
+		flusher.Flush()
+
+		if i < rows && delay > 0 {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(time.Duration(delay) * time.Millisecond):
+			}
+		}
+	}
+
+	w.Header().Set("X-Checksum", "sha256="+hex.EncodeToString(hash.Sum(nil)))
+	w.Header().Set("X-Row-Count", strconv.Itoa(sent))
+}
+
+// preloadAs guesses the preload destination from a path's extension.
+func preloadAs(path string) string {
+	switch {
+	case strings.HasSuffix(path, ".css"):
+		return "style"
+	case strings.HasSuffix(path, ".js"):
+		return "script"
+	case strings.HasSuffix(path, ".woff2"), strings.HasSuffix(path, ".woff"):
+		return "font"
+	case strings.HasSuffix(path, ".png"), strings.HasSuffix(path, ".jpg"), strings.HasSuffix(path, ".svg"):
+		return "image"
+	}
+	return "fetch"
+}
+
+// handleEarlyHints sends a 103 Early Hints response carrying Link preload
+// headers before the final 200, as an alternative to server push.
+func handleEarlyHints(w http.ResponseWriter, r *http.Request) {
+	links := []string{"/pushed-resource-1", "/pushed-resource-2", "/pushed-resource-3"}
+	if linksStr := r.URL.Query().Get("links"); linksStr != "" {
+		links = []string{}
+		for _, l := range strings.Split(linksStr, ",") {
+			if l = strings.TrimSpace(l); l != "" {
+				links = append(links, l)
+			}
+		}
+	}
+
+	for _, l := range links {
+		w.Header().Add("Link", fmt.Sprintf("<%s>; rel=preload; as=%s", l, preloadAs(l)))
+	}
+	w.WriteHeader(http.StatusEarlyHints)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"early_hints": true,
+		"links":       links,
+		"protocol":    r.Proto,
+	})
+	logf(r, "Early hints: sent %d links, proto=%s", len(links), r.Proto)
+}
+
+// handleGoaway asks the server to shut down the connection the request
+// arrived on. The HTTP/2 server treats a "Connection: close" response header
+// as a request to send GOAWAY and close once the connection is idle; over
+// HTTP/1.1 it simply closes the connection after this response.
+func handleGoaway(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Connection", "close")
+	w.WriteHeader(http.StatusOK)
+
+	// The stream ID is not exposed to handlers, so the GOAWAY's last stream
+	// ID (at least this request's stream) can only be observed client-side.
+	w.Write([]byte(fmt.Sprintf(`{"goaway": %t, "protocol": %q, "last_stream_id": null, "note": "last stream ID is not exposed to handlers; it is at least this request's stream"}`,
+		r.ProtoMajor == 2, r.Proto)))
+	logf(r, "GOAWAY requested: proto=%s, remote=%s", r.Proto, r.RemoteAddr)
+}
+
+// handleEcho reflects the request body. The body is read in full (up to
+// maxBody) before responding so X-Body-SHA256 can be sent as a header.
+func handleEcho(maxBody int64, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var body bytes.Buffer
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(&body, hash), http.MaxBytesReader(w, r.Body, maxBody))
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			http.Error(w, fmt.Sprintf("Body exceeds %d bytes", maxBody), http.StatusRequestEntityTooLarge)
+			return
+		}
+		logf(r, "Echo read error after %d bytes: %v", n, err)
+		http.Error(w, "Failed to read body", http.StatusBadRequest)
+		return
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(n, 10))
+	w.Header().Set("X-Body-SHA256", hex.EncodeToString(hash.Sum(nil)))
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, &body); err != nil {
+		logf(r, "Echo write error: %v", err)
+		return
+	}
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	logf(r, "Echo: %d bytes, proto=%s", n, r.Proto)
+}
+
+const (
+	maxLargeHeaderCount = 1000
+	maxLargeHeaderSize  = 64 * 1024
+)
+
+// handleLargeHeaders emits count response headers of size bytes each. Header
+// N is named X-Large-Header-NNNN and its value is "N:" followed by a repeating
+// alphabet, so clients can check every header arrived intact.
+func handleLargeHeaders(w http.ResponseWriter, r *http.Request) {
+	countStr := r.URL.Query().Get("count")
+	count := 100
+	if countStr != "" {
+		if c, err := strconv.Atoi(countStr); err == nil && c >= 0 && c <= maxLargeHeaderCount {
+			count = c
+		}
+	}
+
+	sizeStr := r.URL.Query().Get("size")
+	size := 512
+	if sizeStr != "" {
+		if sz, err := strconv.Atoi(sizeStr); err == nil && sz > 0 && sz <= maxLargeHeaderSize {
+			size = sz
+		}
+	}
+
+	const alphabet = "abcdefghijklmnopqrstuvwxyz"
+	filler := strings.Repeat(alphabet, size/len(alphabet)+1)
+
+	total := 0
+	for i := 1; i <= count; i++ {
+		prefix := fmt.Sprintf("%d:", i)
+		value := prefix
+		if size > len(prefix) {
+			value += filler[:size-len(prefix)]
+		}
+		name := fmt.Sprintf("X-Large-Header-%04d", i)
+		w.Header().Set(name, value)
+		total += len(name) + len(value)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(fmt.Sprintf(`{"headers_set": %d, "value_size": %d, "total_bytes": %d, "protocol": %q}`, count, size, total, r.Proto)))
+	logf(r, "Large headers: count=%d, size=%d, total=%d bytes, proto=%s", count, size, total, r.Proto)
+}
+
+// handlePing echoes the client's send time next to the server's clock so the
+// client can derive one-way and round-trip latency. It skips logging to keep
+// measurement noise down.
+func handlePing(w http.ResponseWriter, r *http.Request) {
+	now := time.Now().UnixNano()
+	t, err := strconv.ParseInt(r.URL.Query().Get("t"), 10, 64)
+	if err != nil {
+		http.Error(w, "t must be a unix timestamp in nanoseconds", http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write([]byte(fmt.Sprintf(`{"client":%d,"server":%d}`, t, now)))
+}
+
+// serverConfig is served on /config. It echoes the HTTP/2 settings the
+// server advertises (after validation), so a client can tell whether a
+// limit it hit came from here or from a proxy in between.
+type serverConfig struct {
+	Server               string `json:"server"`
+	Addr                 string `json:"addr"`
+	TLS                  bool   `json:"tls"`
+	TLSAddr              string `json:"tls_addr,omitempty"`
+	Dual                 bool   `json:"dual"`
+	H2C                  bool   `json:"h2c"`
+	Compression          bool   `json:"compression"`
+	MaxConcurrentStreams uint   `json:"max_concurrent_streams"`
+	MaxReadFrameSize     uint   `json:"max_read_frame_size"`
+	IdleTimeoutMs        int64  `json:"idle_timeout_ms"`
+	ReadIdleTimeoutMs    int64  `json:"read_idle_timeout_ms"`
+	PingTimeoutMs        int64  `json:"ping_timeout_ms"`
+	MaxBody              int64  `json:"max_body"`
+	Metrics              bool   `json:"metrics"`
+	ShutdownTimeoutMs    int64  `json:"shutdown_timeout_ms"`
+	LogFormat            string `json:"log_format"`
+	ClientAuth           string `json:"client_auth"`
+}
+
+func handleConfig(cfg serverConfig, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cfg)
+}
+
+// handleReadyz fails once shutdown has begun, so load balancers stop sending
+// new requests while in-flight ones drain. /livez only says the process is
+// up.
+func handleReadyz(shuttingDown *atomic.Bool, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if shuttingDown.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"status":"unavailable","reason":"shutting down"}`))
+		return
+	}
+	w.Write([]byte(`{"status":"ok"}`))
+}
+
 func handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte(`{"status":"ok"}`))
 }
 
+// pingWatchListener wraps accepted connections so the server can log
+// connections it closes after an unanswered keepalive PING. The HTTP/2 server
+// only reports that with verbose logging enabled.
+type pingWatchListener struct {
+	net.Listener
+	threshold    time.Duration
+	shuttingDown *atomic.Bool
+}
+
+func (l *pingWatchListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	pc := &pingWatchConn{Conn: conn, threshold: l.threshold, shuttingDown: l.shuttingDown}
+	pc.lastRead.Store(time.Now().UnixNano())
+	return pc, nil
+}
+
+type pingWatchConn struct {
+	net.Conn
+	threshold    time.Duration
+	shuttingDown *atomic.Bool
+	lastRead     atomic.Int64
+	readErr      atomic.Bool
+	closeOnce    sync.Once
+}
+
+func (c *pingWatchConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	if n > 0 {
+		c.lastRead.Store(time.Now().UnixNano())
+	}
+	// Deadline errors come from net/http itself (e.g. when h2c hijacks the
+	// connection), not from the peer going away.
+	var netErr net.Error
+	if err != nil && !(errors.As(err, &netErr) && netErr.Timeout()) {
+		c.readErr.Store(true)
+	}
+	return n, err
+}
+
+// Close logs when the server tears down a connection that has been silent
+// for at least ReadIdleTimeout+PingTimeout, i.e. a PING went unanswered.
+// Connections closed by the peer fail a read first, and idle connections
+// closed during shutdown are expected, so neither is reported.
+func (c *pingWatchConn) Close() error {
+	c.closeOnce.Do(func() {
+		idle := time.Since(time.Unix(0, c.lastRead.Load()))
+		if !c.readErr.Load() && !c.shuttingDown.Load() && idle >= c.threshold {
+			log.Printf("Closing connection from %s: no PING response (silent for %s)", c.RemoteAddr(), idle.Round(time.Millisecond))
+		}
+	})
+	return c.Conn.Close()
+}
+
 const clientHTML = `<!DOCTYPE html>
 <html>
 <head>
@@ -345,48 +1450,312 @@ const clientHTML = `<!DOCTYPE html>
 </body>
 </html>`
 
+// loadClientAuth maps -client-auth and -client-ca onto tls.Config settings.
+// Without a CA, presented certificates are accepted unverified so /info can
+// still report them.
+func loadClientAuth(mode, caFile string) (tls.ClientAuthType, *x509.CertPool, error) {
+	var pool *x509.CertPool
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return tls.NoClientCert, nil, fmt.Errorf("reading -client-ca: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return tls.NoClientCert, nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+	}
+
+	switch mode {
+	case "":
+		if pool != nil {
+			return tls.VerifyClientCertIfGiven, pool, nil
+		}
+		return tls.NoClientCert, nil, nil
+	case "request":
+		if pool != nil {
+			return tls.VerifyClientCertIfGiven, pool, nil
+		}
+		return tls.RequestClientCert, nil, nil
+	case "require":
+		if pool != nil {
+			return tls.RequireAndVerifyClientCert, pool, nil
+		}
+		return tls.RequireAnyClientCert, nil, nil
+	default:
+		return tls.NoClientCert, nil, fmt.Errorf("invalid -client-auth %q (want request or require)", mode)
+	}
+}
+
 func main() {
 	addr := flag.String("addr", ":8080", "HTTP service address")
 	tlsCert := flag.String("cert", "", "TLS certificate file (enables HTTPS/H2)")
 	tlsKey := flag.String("key", "", "TLS key file")
 	h2cEnabled := flag.Bool("h2c", true, "Enable h2c (HTTP/2 cleartext) when not using TLS")
+	dual := flag.Bool("dual", false, "Serve h2c on -addr and TLS h2 on -tls-addr at the same time (requires -cert and -key)")
+	tlsAddr := flag.String("tls-addr", ":8443", "HTTPS service address when -dual is set")
+	compression := flag.String("compression", "on", "Response compression for /info, /multiplex and /download (on or off)")
+	maxConcurrentStreams := flag.Uint("max-concurrent-streams", 0, "HTTP/2 SETTINGS_MAX_CONCURRENT_STREAMS (0 = library default)")
+	maxReadFrameSize := flag.Uint("max-read-frame-size", 0, "HTTP/2 SETTINGS_MAX_FRAME_SIZE advertised to peers, 16384-16777215 (0 = library default)")
+	idleTimeout := flag.Duration("idle-timeout", 0, "Close HTTP/2 connections idle for this long (0 = no timeout)")
+	readIdleTimeout := flag.Duration("read-idle-timeout", 0, "Send an HTTP/2 PING after no frames are read for this long (0 = no PINGs)")
+	pingTimeout := flag.Duration("ping-timeout", 15*time.Second, "Close the connection if a PING is not answered within this long")
+	maxBody := flag.Int64("max-body", 10<<20, "Maximum request body size accepted by /echo, in bytes")
+	metrics := flag.Bool("metrics", true, "Expose Prometheus metrics on /metrics")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 10*time.Second, "Grace period for in-flight requests on shutdown")
+	logFormat := flag.String("log-format", "text", "Log output format: text or json")
+	clientCA := flag.String("client-ca", "", "PEM file of CAs used to verify client certificates (TLS only)")
+	clientAuth := flag.String("client-auth", "", "Client certificate policy: request or require (default request when -client-ca is set)")
 	flag.Parse()
 
+	if *logFormat == "json" {
+		jsonLogs = true
+		slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stderr, nil)))
+	}
+
+	tlsEnabled := *tlsCert != "" && *tlsKey != ""
+	if *dual && !tlsEnabled {
+		log.Fatal("-dual requires -cert and -key")
+	}
+
+	clientAuthType, clientCAs, err := loadClientAuth(*clientAuth, *clientCA)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if clientAuthType != tls.NoClientCert && !tlsEnabled {
+		log.Fatal("-client-ca and -client-auth require -cert and -key")
+	}
+	// x/net silently falls back to its default for out-of-range values, and
+	// both settings are uint32 on the wire, so either would make the
+	// startup log and /config wrong.
+	if *maxReadFrameSize != 0 && (*maxReadFrameSize < 1<<14 || *maxReadFrameSize > 1<<24-1) {
+		log.Fatalf("-max-read-frame-size must be 0 or between %d and %d", 1<<14, 1<<24-1)
+	}
+	if uint64(*maxConcurrentStreams) > math.MaxUint32 {
+		log.Fatalf("-max-concurrent-streams must be at most %d", uint64(math.MaxUint32))
+	}
+
+	cfg := serverConfig{
+		Server:               "http2",
+		Addr:                 *addr,
+		TLS:                  tlsEnabled,
+		Dual:                 *dual,
+		H2C:                  *h2cEnabled && (!tlsEnabled || *dual),
+		Compression:          *compression != "off",
+		MaxConcurrentStreams: *maxConcurrentStreams,
+		MaxReadFrameSize:     *maxReadFrameSize,
+		IdleTimeoutMs:        idleTimeout.Milliseconds(),
+		ReadIdleTimeoutMs:    readIdleTimeout.Milliseconds(),
+		PingTimeoutMs:        pingTimeout.Milliseconds(),
+		MaxBody:              *maxBody,
+		Metrics:              *metrics,
+		ShutdownTimeoutMs:    shutdownTimeout.Milliseconds(),
+		LogFormat:            *logFormat,
+		ClientAuth:           clientAuthType.String(),
+	}
+	if *dual {
+		cfg.TLSAddr = *tlsAddr
+	}
+
+	compressed := func(h http.HandlerFunc) http.HandlerFunc {
+		if *compression == "off" {
+			return h
+		}
+		return withCompression(h)
+	}
+
+	var shuttingDown atomic.Bool
+
 	mux := http.NewServeMux()
-	mux.HandleFunc("/info", handleInfo)
-	mux.HandleFunc("/push", handlePush)
-	mux.HandleFunc("/pushed-resource-1", handlePushedResource)
-	mux.HandleFunc("/pushed-resource-2", handlePushedResource)
-	mux.HandleFunc("/pushed-resource-3", handlePushedResource)
-	mux.HandleFunc("/multiplex", handleMultiplex)
+	mux.HandleFunc("/info", compressed(handleInfo))
+	mux.HandleFunc("/push", func(w http.ResponseWriter, r *http.Request) {
+		handlePush(mux, w, r)
+	})
+	for i := 1; i <= maxPushCount; i++ {
+		mux.HandleFunc(fmt.Sprintf("/pushed-resource-%d", i), handlePushedResource)
+	}
+	mux.HandleFunc("/push-nested", handlePushNested)
+	mux.HandleFunc("/multiplex", compressed(handleMultiplex))
+	mux.HandleFunc("/download", compressed(handleDownload))
 	mux.HandleFunc("/concurrent", handleConcurrent)
+	mux.HandleFunc("/status/", handleStatus)
+	mux.HandleFunc("/goaway", handleGoaway)
+	mux.HandleFunc("/early-hints", handleEarlyHints)
+	mux.HandleFunc("/trailers", handleTrailers)
+	mux.HandleFunc("/drip", handleDrip)
+	mux.HandleFunc("/ping", handlePing)
+	mux.HandleFunc("/large-headers", handleLargeHeaders)
+	mux.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		handleEcho(*maxBody, w, r)
+	})
+	mux.HandleFunc("/config", func(w http.ResponseWriter, r *http.Request) {
+		handleConfig(cfg, w, r)
+	})
 	mux.HandleFunc("/health", handleHealth)
+	mux.HandleFunc("/livez", handleHealth)
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		handleReadyz(&shuttingDown, w, r)
+	})
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/html")
 		w.Write([]byte(clientHTML))
 	})
 
-	if *tlsCert != "" && *tlsKey != "" {
-		server := &http.Server{
-			Addr:    *addr,
-			Handler: mux,
-			TLSConfig: &tls.Config{
-				NextProtos: []string{"h2", "http/1.1"},
+	// Request contexts derive from baseCtx so in-flight streams can be
+	// cancelled once the shutdown grace period runs out.
+	baseCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+
+	var handler http.Handler = mux
+	if *metrics {
+		prometheus.MustRegister(requestsTotal, requestDuration, inflightStreams)
+		mux.Handle("/metrics", promhttp.Handler())
+		handler = withMetrics(mux, handler)
+	}
+	handler = withRequestID(withConnRequests(withAccessLog(handler)))
+
+	// openConns counts connections served by net/http, h2cConns those handed
+	// over to h2c (see withH2CConns).
+	var openConns, h2cConns atomic.Int64
+	newServer := func(addr string, handler http.Handler) *http.Server {
+		return &http.Server{
+			Addr:        addr,
+			Handler:     handler,
+			BaseContext: func(net.Listener) context.Context { return baseCtx },
+			ConnContext: func(ctx context.Context, c net.Conn) context.Context {
+				return context.WithValue(ctx, connInfoKey{}, &connInfo{id: newRequestID()})
+			},
+			ConnState: func(conn net.Conn, state http.ConnState) {
+				switch state {
+				case http.StateNew:
+					openConns.Add(1)
+				// Hijacked h2c connections are counted by withH2CConns until
+				// they close.
+				case http.StateClosed, http.StateHijacked:
+					openConns.Add(-1)
+				}
 			},
 		}
-		http2.ConfigureServer(server, &http2.Server{})
+	}
 
-		log.Printf("Starting HTTP/2 (h2) server on %s", *addr)
-		log.Fatal(server.ListenAndServeTLS(*tlsCert, *tlsKey))
-	} else {
-		var handler http.Handler = mux
+	// -idle-timeout and the PING keepalive are independent: the idle timeout
+	// closes connections with no open streams even if PINGs are answered
+	// (PING acks don't count as activity), while -read-idle-timeout and
+	// -ping-timeout close connections whose peer stopped responding, busy or
+	// not.
+	newH2Server := func() *http2.Server {
+		return &http2.Server{
+			MaxConcurrentStreams: uint32(*maxConcurrentStreams),
+			MaxReadFrameSize:     uint32(*maxReadFrameSize),
+			IdleTimeout:          *idleTimeout,
+			ReadIdleTimeout:      *readIdleTimeout,
+			PingTimeout:          *pingTimeout,
+		}
+	}
+	log.Printf("HTTP/2 settings: max-concurrent-streams=%d, max-read-frame-size=%d, idle-timeout=%s, read-idle-timeout=%s, ping-timeout=%s (0 = default)",
+		*maxConcurrentStreams, *maxReadFrameSize, *idleTimeout, *readIdleTimeout, *pingTimeout)
+
+	listenAndServe := func(server *http.Server, serve func(net.Listener) error) func() error {
+		return func() error {
+			ln, err := net.Listen("tcp", server.Addr)
+			if err != nil {
+				return err
+			}
+			if *readIdleTimeout > 0 {
+				ln = &pingWatchListener{Listener: ln, threshold: *readIdleTimeout + *pingTimeout, shuttingDown: &shuttingDown}
+			}
+			return serve(ln)
+		}
+	}
+
+	var servers []*http.Server
+	var listeners []func() error
+
+	if tlsEnabled {
+		listenAddr := *addr
+		if *dual {
+			listenAddr = *tlsAddr
+		}
+		server := newServer(listenAddr, handler)
+		server.TLSConfig = &tls.Config{
+			NextProtos: []string{"h2", "http/1.1"},
+			ClientAuth: clientAuthType,
+			ClientCAs:  clientCAs,
+		}
+		http2.ConfigureServer(server, newH2Server())
+
+		log.Printf("Starting HTTP/2 (h2) server on %s (client auth: %s)", listenAddr, clientAuthType)
+		servers = append(servers, server)
+		listeners = append(listeners, listenAndServe(server, func(ln net.Listener) error {
+			return server.ServeTLS(ln, *tlsCert, *tlsKey)
+		}))
+	}
+
+	if !tlsEnabled || *dual {
+		server := newServer(*addr, handler)
 		if *h2cEnabled {
-			h2s := &http2.Server{}
-			handler = h2c.NewHandler(mux, h2s)
+			// ConfigureServer hooks the h2 server into Shutdown, which then
+			// sends GOAWAY on h2c connections too.
+			h2s := newH2Server()
+			http2.ConfigureServer(server, h2s)
+			server.Handler = withH2CConns(h2c.NewHandler(handler, h2s), &h2cConns)
 			log.Printf("Starting HTTP/2 (h2c) server on %s", *addr)
 		} else {
 			log.Printf("Starting HTTP/1.1 server on %s", *addr)
 		}
-		log.Fatal(http.ListenAndServe(*addr, handler))
+		servers = append(servers, server)
+		listeners = append(listeners, listenAndServe(server, server.Serve))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errc := make(chan error, len(listeners))
+	for _, listen := range listeners {
+		go func(listen func() error) {
+			errc <- listen()
+		}(listen)
+	}
+
+	// A failing listener takes the whole process down, but the remaining
+	// servers are still drained first.
+	var listenErr error
+	select {
+	case listenErr = <-errc:
+		log.Printf("Listener failed: %v", listenErr)
+	case <-ctx.Done():
+	}
+	stop()
+	shuttingDown.Store(true)
+
+	log.Printf("Shutting down: %d open connections, grace period %s", openConns.Load()+h2cConns.Load(), *shutdownTimeout)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, server := range servers {
+		wg.Add(1)
+		go func(server *http.Server) {
+			defer wg.Done()
+			if err := server.Shutdown(shutdownCtx); err != nil {
+				log.Printf("Graceful shutdown of %s incomplete (%v), cancelling %d open connections", server.Addr, err, openConns.Load()+h2cConns.Load())
+				cancelRequests()
+				server.Close()
+			}
+		}(server)
+	}
+	wg.Wait()
+
+	// Shutdown has sent GOAWAY to the h2c connections but doesn't wait for
+	// them; give their in-flight streams the rest of the grace period.
+	if err := waitDrained(shutdownCtx, &h2cConns); err != nil {
+		log.Printf("Graceful shutdown incomplete (%v), cancelling %d h2c connections", err, h2cConns.Load())
+		cancelRequests()
+	}
+
+	if listenErr != nil {
+		log.Fatalf("Server stopped: %v", listenErr)
 	}
+	log.Printf("Server stopped")
 }
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestHandleInfoEscapesHeaders(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/info?q=%22quoted%22", nil)
+	// Set directly: these values could not cross the wire, but the handler
+	// must still produce valid JSON for them.
+	req.Header["X-Quotes"] = []string{`say "hi"`}
+	req.Header["X-Newlines"] = []string{"line1\nline2\r\n\ttabbed"}
+	req.Header["X-Multi"] = []string{"a", "b, c"}
+	rec := httptest.NewRecorder()
+
+	handleInfo(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", ct)
+	}
+
+	var got infoResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("response is not valid JSON: %v\n%s", err, rec.Body.String())
+	}
+	for _, name := range []string{"X-Quotes", "X-Newlines", "X-Multi"} {
+		if !reflect.DeepEqual(got.Headers[name], req.Header[name]) {
+			t.Errorf("header %s = %q, want %q", name, got.Headers[name], req.Header[name])
+		}
+	}
+	if got.URL != req.URL.String() {
+		t.Errorf("url = %q, want %q", got.URL, req.URL.String())
+	}
+	if got.Method != http.MethodGet || got.Host != req.Host {
+		t.Errorf("method/host = %q/%q, want %q/%q", got.Method, got.Host, http.MethodGet, req.Host)
+	}
+}
@@ -1,89 +1,487 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
+	"fmt"
 	"log"
+	"math"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// defaultTopic is used when a request doesn't name a topic.
+const defaultTopic = "default"
+
+// maxMessagesPerTopic caps how many messages each topic retains.
+const maxMessagesPerTopic = 100
+
 type Message struct {
 	ID        int       `json:"id"`
+	Topic     string    `json:"topic"`
 	Text      string    `json:"text"`
 	Timestamp time.Time `json:"timestamp"`
 }
 
+// Event is an ephemeral notification (typing, presence...). Events go only to
+// pollers waiting at the moment they are emitted and are never stored.
+type Event struct {
+	Topic     string    `json:"topic"`
+	Type      string    `json:"type"`
+	Data      string    `json:"data,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// eventBuffer is how many events a single waiting poller can queue before
+// further ones are dropped for it.
+const eventBuffer = 16
+
+// MessageBroker stores messages per topic. IDs are allocated from a single
+// counter, so they are globally unique and increase across topics; a poller's
+// since cursor is still only compared against its own topic.
+//
+// Waiting pollers block on a per-topic channel that AddMessage closes, so
+// they wake as soon as a message arrives instead of re-checking on a timer.
 type MessageBroker struct {
-	mu       sync.RWMutex
-	messages []Message
-	nextID   int
+	mu      sync.RWMutex
+	topics  map[string][]Message
+	nextID  int
+	waiters map[string]chan struct{}
+
+	// totalAdded counts every message ever added; Clear doesn't reset it.
+	totalAdded int
+
+	// listeners holds, per topic, a channel for each poller currently
+	// waiting, for delivering events.
+	listeners map[string]map[chan Event]struct{}
+
+	// ttl is how long messages are kept (0 = until pushed out by the cap).
+	ttl time.Duration
 }
 
-func NewMessageBroker() *MessageBroker {
+func NewMessageBroker(ttl time.Duration) *MessageBroker {
 	return &MessageBroker{
-		messages: make([]Message, 0),
-		nextID:   1,
+		topics:    make(map[string][]Message),
+		nextID:    1,
+		waiters:   make(map[string]chan struct{}),
+		listeners: make(map[string]map[chan Event]struct{}),
+		ttl:       ttl,
 	}
 }
 
-func (mb *MessageBroker) AddMessage(text string) Message {
+func (mb *MessageBroker) expired(msg Message, now time.Time) bool {
+	return mb.ttl > 0 && now.Sub(msg.Timestamp) > mb.ttl
+}
+
+// Sweep drops expired messages from every topic and returns how many were
+// removed. Readers skip expired messages on their own, so this only bounds
+// memory between sweeps.
+func (mb *MessageBroker) Sweep() int {
 	mb.mu.Lock()
 	defer mb.mu.Unlock()
 
-	msg := Message{
-		ID:        mb.nextID,
-		Text:      text,
-		Timestamp: time.Now(),
+	now := time.Now()
+	removed := 0
+	for topic, messages := range mb.topics {
+		// Messages are appended in time order, so expired ones form a prefix.
+		n := 0
+		for n < len(messages) && mb.expired(messages[n], now) {
+			n++
+		}
+		if n == len(messages) {
+			delete(mb.topics, topic)
+		} else if n > 0 {
+			mb.topics[topic] = append([]Message(nil), messages[n:]...)
+		}
+		removed += n
 	}
-	mb.nextID++
-	mb.messages = append(mb.messages, msg)
+	return removed
+}
 
-	if len(mb.messages) > 100 {
-		mb.messages = mb.messages[len(mb.messages)-100:]
+// runSweeper calls Sweep periodically until the process exits.
+func (mb *MessageBroker) runSweeper(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if removed := mb.Sweep(); removed > 0 {
+			log.Printf("Expired %d messages older than %s", removed, mb.ttl)
+		}
 	}
+}
 
-	return msg
+func (mb *MessageBroker) AddMessage(topic, text string) Message {
+	return mb.AddMessages(topic, []string{text})[0]
 }
 
-func (mb *MessageBroker) GetMessagesSince(sinceID int, timeout time.Duration) []Message {
-	start := time.Now()
-	for {
-		mb.mu.RLock()
+// AddMessages appends texts to topic in order under a single lock, so they
+// get consecutive IDs and pollers see either none or all of them. Callers
+// keep batches within maxMessagesPerTopic, or the oldest of them are
+// dropped straight away.
+func (mb *MessageBroker) AddMessages(topic string, texts []string) []Message {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	now := time.Now()
+	added := make([]Message, len(texts))
+	for i, text := range texts {
+		added[i] = Message{
+			ID:        mb.nextID,
+			Topic:     topic,
+			Text:      text,
+			Timestamp: now,
+		}
+		mb.nextID++
+	}
+	mb.totalAdded += len(added)
+	messages := append(mb.topics[topic], added...)
+
+	if len(messages) > maxMessagesPerTopic {
+		messages = messages[len(messages)-maxMessagesPerTopic:]
+	}
+	mb.topics[topic] = messages
+
+	if ch, ok := mb.waiters[topic]; ok {
+		close(ch)
+		delete(mb.waiters, topic)
+	}
+
+	return added
+}
+
+// SuggestedRetry estimates how long a poller that just got an empty,
+// hold-capped response can wait before polling again without adding
+// latency: it projects the topic's average message interval from its
+// newest message and subtracts the hold the next poll will cover. It
+// returns 0 (re-poll right away) when there are too few messages to judge.
+func (mb *MessageBroker) SuggestedRetry(topic string, hold time.Duration) time.Duration {
+	mb.mu.RLock()
+	defer mb.mu.RUnlock()
+
+	messages := mb.topics[topic]
+	if len(messages) < 2 {
+		return 0
+	}
+	first, last := messages[0].Timestamp, messages[len(messages)-1].Timestamp
+	interval := last.Sub(first) / time.Duration(len(messages)-1)
+	return max(time.Until(last.Add(interval))-hold, 0)
+}
+
+// Notify delivers an event to every poller currently waiting on topic and
+// returns how many received it.
+func (mb *MessageBroker) Notify(topic, eventType, data string) int {
+	mb.mu.RLock()
+	defer mb.mu.RUnlock()
+
+	ev := Event{Topic: topic, Type: eventType, Data: data, Timestamp: time.Now()}
+	delivered := 0
+	for ch := range mb.listeners[topic] {
+		select {
+		case ch <- ev:
+			delivered++
+		default:
+		}
+	}
+	return delivered
+}
+
+func (mb *MessageBroker) removeListener(topic string, ch chan Event) {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
+
+	delete(mb.listeners[topic], ch)
+	if len(mb.listeners[topic]) == 0 {
+		delete(mb.listeners, topic)
+	}
+}
+
+// GetMessagesSince returns the topic's messages newer than sinceID, waiting
+// up to timeout for one to arrive. It returns empty slices on timeout or
+// once ctx is done. live reports whether the messages arrived while waiting
+// (the backlog was empty on entry) rather than being catch-up. Events
+// emitted while waiting also end the wait and are returned on their own.
+func (mb *MessageBroker) GetMessagesSince(ctx context.Context, topic string, sinceID int, timeout time.Duration) (messages []Message, events []Event, live bool) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	var listener chan Event
+	defer func() {
+		if listener != nil {
+			mb.removeListener(topic, listener)
+		}
+	}()
+
+	for waited := false; ; waited = true {
+		mb.mu.Lock()
+		now := time.Now()
 		var newMessages []Message
-		for _, msg := range mb.messages {
-			if msg.ID > sinceID {
+		for _, msg := range mb.topics[topic] {
+			if msg.ID > sinceID && !mb.expired(msg, now) {
 				newMessages = append(newMessages, msg)
 			}
 		}
-		mb.mu.RUnlock()
-
 		if len(newMessages) > 0 {
-			return newMessages
+			mb.mu.Unlock()
+			return newMessages, []Event{}, waited
+		}
+		wake, ok := mb.waiters[topic]
+		if !ok {
+			wake = make(chan struct{})
+			mb.waiters[topic] = wake
+		}
+		if listener == nil {
+			listener = make(chan Event, eventBuffer)
+			if mb.listeners[topic] == nil {
+				mb.listeners[topic] = make(map[chan Event]struct{})
+			}
+			mb.listeners[topic][listener] = struct{}{}
 		}
+		mb.mu.Unlock()
+
+		select {
+		case <-wake:
+		case ev := <-listener:
+			events = append(events, ev)
+			for len(listener) > 0 {
+				events = append(events, <-listener)
+			}
+			return []Message{}, events, false
+		case <-timer.C:
+			return []Message{}, []Event{}, false
+		case <-ctx.Done():
+			return []Message{}, []Event{}, false
+		}
+	}
+}
 
-		if time.Since(start) >= timeout {
-			return []Message{}
+func (mb *MessageBroker) GetAllMessages(topic string) []Message {
+	mb.mu.RLock()
+	defer mb.mu.RUnlock()
+
+	now := time.Now()
+	result := make([]Message, 0, len(mb.topics[topic]))
+	for _, msg := range mb.topics[topic] {
+		if !mb.expired(msg, now) {
+			result = append(result, msg)
 		}
+	}
+	return result
+}
+
+// Clear removes every message in every topic and restarts IDs at 1,
+// returning how many messages were removed. Pollers holding a since cursor
+// from before the reset should start again from 0.
+func (mb *MessageBroker) Clear() int {
+	mb.mu.Lock()
+	defer mb.mu.Unlock()
 
-		time.Sleep(100 * time.Millisecond)
+	removed := 0
+	for _, messages := range mb.topics {
+		removed += len(messages)
 	}
+	mb.topics = make(map[string][]Message)
+	mb.nextID = 1
+	return removed
 }
 
-func (mb *MessageBroker) GetAllMessages() []Message {
+func (mb *MessageBroker) TotalAdded() int {
 	mb.mu.RLock()
 	defer mb.mu.RUnlock()
+	return mb.totalAdded
+}
 
-	result := make([]Message, len(mb.messages))
-	copy(result, mb.messages)
-	return result
+// holdBucketsMs are the upper bounds of the hold-duration histogram.
+var holdBucketsMs = [...]int64{10, 50, 100, 250, 500, 1000, 2500, 5000, 10000, 30000, 60000}
+
+// pollStats tracks held requests for /stats. Percentiles come from a fixed
+// histogram, so p95 is reported as the upper bound of its bucket.
+type pollStats struct {
+	waiting   atomic.Int64
+	served    atomic.Int64
+	abandoned atomic.Int64
+
+	mu      sync.Mutex
+	totalMs int64
+	buckets [len(holdBucketsMs) + 1]int64
+}
+
+var stats pollStats
+
+func (ps *pollStats) recordHold(d time.Duration) {
+	ms := d.Milliseconds()
+	i := 0
+	for i < len(holdBucketsMs) && ms > holdBucketsMs[i] {
+		i++
+	}
+
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+	ps.totalMs += ms
+	ps.buckets[i]++
+}
+
+// holdSummary returns the mean and p95 hold in milliseconds over served
+// polls; a p95 of -1 means it fell in the overflow bucket (over 60s).
+func (ps *pollStats) holdSummary() (avgMs float64, p95Ms int64) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	var count int64
+	for _, n := range ps.buckets {
+		count += n
+	}
+	if count == 0 {
+		return 0, 0
+	}
+
+	target := (count*95 + 99) / 100
+	var seen int64
+	p95Ms = -1
+	for i, n := range ps.buckets[:len(holdBucketsMs)] {
+		seen += n
+		if seen >= target {
+			p95Ms = holdBucketsMs[i]
+			break
+		}
+	}
+	return float64(ps.totalMs) / float64(count), p95Ms
+}
+
+// ackTracker records, per client and topic, the highest message ID the client
+// acknowledged and the highest one it was sent. Polls that name a client_id
+// start after the acknowledged ID, so anything sent but not acknowledged
+// (say, a response a proxy dropped) is delivered again.
+type ackTracker struct {
+	mu      sync.Mutex
+	cursors map[ackKey]*ackCursor
+}
+
+type ackKey struct {
+	clientID string
+	topic    string
+}
+
+type ackCursor struct {
+	acked     int
+	delivered int
+}
+
+var acks = ackTracker{cursors: make(map[ackKey]*ackCursor)}
+
+func (at *ackTracker) cursor(clientID, topic string) *ackCursor {
+	key := ackKey{clientID, topic}
+	c, ok := at.cursors[key]
+	if !ok {
+		c = &ackCursor{}
+		at.cursors[key] = c
+	}
+	return c
+}
+
+// Reset forgets every client's cursors, e.g. after message IDs restart.
+func (at *ackTracker) Reset() {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	at.cursors = make(map[ackKey]*ackCursor)
+}
+
+// Acked returns the highest message ID clientID has acknowledged on topic.
+func (at *ackTracker) Acked(clientID, topic string) int {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	return at.cursor(clientID, topic).acked
+}
+
+// Ack moves clientID's acknowledged ID on topic forward to upTo and returns
+// the resulting value; acknowledgements never move backwards.
+func (at *ackTracker) Ack(clientID, topic string, upTo int) int {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	c := at.cursor(clientID, topic)
+	c.acked = max(c.acked, upTo)
+	return c.acked
+}
+
+// Delivered records messages sent to clientID and returns how many of them
+// had already been sent before.
+func (at *ackTracker) Delivered(clientID, topic string, messages []Message) int {
+	at.mu.Lock()
+	defer at.mu.Unlock()
+	c := at.cursor(clientID, topic)
+	redelivered := 0
+	for _, msg := range messages {
+		if msg.ID <= c.delivered {
+			redelivered++
+		}
+		c.delivered = max(c.delivered, msg.ID)
+	}
+	return redelivered
+}
+
+// topicParam returns the ?topic= query parameter, or defaultTopic.
+func topicParam(r *http.Request) string {
+	if topic := r.URL.Query().Get("topic"); topic != "" {
+		return topic
+	}
+	return defaultTopic
 }
 
 var broker *MessageBroker
 
-func handlePoll(w http.ResponseWriter, r *http.Request) {
+// pollETag identifies the newest message a poll response covers for topic.
+// The topic is query-escaped, since it may contain quotes or other bytes an
+// entity tag can't carry.
+func pollETag(topic string, lastID int) string {
+	return fmt.Sprintf(`"%s:%d"`, url.QueryEscape(topic), lastID)
+}
+
+// parsePollETag extracts the message ID from an If-None-Match value produced
+// by pollETag for topic. If-None-Match uses weak comparison, so a W/ prefix
+// (added by intermediaries that transform the body) is ignored.
+func parsePollETag(value, topic string) (int, bool) {
+	value = strings.TrimPrefix(strings.TrimSpace(value), "W/")
+	inner, ok := strings.CutPrefix(value, `"`+url.QueryEscape(topic)+":")
+	if !ok || !strings.HasSuffix(inner, `"`) {
+		return 0, false
+	}
+	id, err := strconv.Atoi(strings.TrimSuffix(inner, `"`))
+	return id, err == nil
+}
+
+type pollConfig struct {
+	maxHold   time.Duration
+	pushHints bool
+}
+
+// pollHintPath is the pushed resource telling a client where to poll next.
+func pollHintPath(topic string, since int) string {
+	return "/poll-hint?" + url.Values{"topic": {topic}, "since": {strconv.Itoa(since)}}.Encode()
+}
+
+// handlePoll holds the request until a message arrives or the timeout
+// expires. A non-zero cfg.maxHold caps the hold below the client's timeout (as a
+// proxy with a shorter idle timeout would force); an empty response cut short
+// that way carries X-Suggested-Retry-Ms (how long the client can wait before
+// re-polling, from the topic's message rate; see SuggestedRetry) and
+// X-Max-Hold-Ms, so the client can lower its timeout to match.
+//
+// Responses carry an ETag naming the newest message ID they cover. A client
+// may send it back in If-None-Match instead of ?since=; the request is still
+// held as usual, and only a poll that ends with nothing new answers 304 Not
+// Modified rather than an empty 200. A 304 that shows up before the hold
+// could have expired therefore came from an intermediary's cache.
+//
+// With cfg.pushHints, HTTP/2 clients are also pushed /poll-hint naming the
+// since value for their next poll, so push-assisted and plain long polling
+// can be compared.
+func handlePoll(cfg pollConfig, w http.ResponseWriter, r *http.Request) {
 	sinceIDStr := r.URL.Query().Get("since")
 	sinceID := 0
 	if sinceIDStr != "" {
@@ -100,29 +498,191 @@ func handlePoll(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	log.Printf("Poll request: since=%d, timeout=%v", sinceID, timeout)
+	hold := timeout
+	if cfg.maxHold > 0 && hold > cfg.maxHold {
+		hold = cfg.maxHold
+	}
+
+	topic := topicParam(r)
+
+	matchID, hasMatch := parsePollETag(r.Header.Get("If-None-Match"), topic)
+	if sinceIDStr == "" && hasMatch {
+		sinceID = matchID
+	}
+
+	// With client_id the server owns the cursor (at-least-once delivery):
+	// since and If-None-Match are ignored in favour of the last /ack.
+	clientID := r.URL.Query().Get("client_id")
+	if clientID != "" {
+		sinceID = acks.Acked(clientID, topic)
+	}
+
+	log.Printf("Poll request: topic=%s, since=%d, client_id=%s, timeout=%v, hold=%v", topic, sinceID, clientID, timeout, hold)
+
+	start := time.Now()
+	stats.waiting.Add(1)
+	messages, events, live := broker.GetMessagesSince(r.Context(), topic, sinceID, hold)
+	stats.waiting.Add(-1)
+	if err := r.Context().Err(); err != nil {
+		stats.abandoned.Add(1)
+		log.Printf("Poll abandoned: topic=%s, since=%d, client gone after %s", topic, sinceID, time.Since(start).Round(time.Millisecond))
+		return
+	}
+
+	stats.served.Add(1)
+	stats.recordHold(time.Since(start))
+
+	capped := len(messages) == 0 && len(events) == 0 && hold < timeout
+	if capped {
+		retry := broker.SuggestedRetry(topic, hold)
+		w.Header().Set("X-Suggested-Retry-Ms", strconv.FormatInt(retry.Milliseconds(), 10))
+		w.Header().Set("X-Max-Hold-Ms", strconv.FormatInt(cfg.maxHold.Milliseconds(), 10))
+		log.Printf("Poll hold capped: topic=%s, returned empty after %s of requested %s", topic, hold, timeout)
+	}
+
+	// Catch-up batches were already waiting when the poll arrived; anything
+	// else arrived while the request was held. A proxy that buffers held
+	// responses shows up as live batches arriving late.
+	deliveredDuringHold := 0
+	if live {
+		deliveredDuringHold = len(messages)
+	}
+
+	lastID := sinceID
+	if len(messages) > 0 {
+		lastID = messages[len(messages)-1].ID
+	}
+	etag := pollETag(topic, lastID)
+	w.Header().Set("ETag", etag)
+	if len(messages) == 0 && len(events) == 0 && hasMatch && matchID == lastID {
+		log.Printf("Poll not modified: topic=%s, etag=%s", topic, etag)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	resp := map[string]interface{}{
+		"messages":              messages,
+		"count":                 len(messages),
+		"events":                events,
+		"hold_capped":           capped,
+		"delivered_during_hold": deliveredDuringHold,
+		"catch_up":              len(messages) - deliveredDuringHold,
+	}
+	if clientID != "" {
+		redelivered := acks.Delivered(clientID, topic, messages)
+		if redelivered > 0 {
+			log.Printf("Redelivering %d unacknowledged messages to client_id=%s, topic=%s", redelivered, clientID, topic)
+		}
+		resp["client_id"] = clientID
+		resp["acked_up_to"] = sinceID
+		resp["redelivered"] = redelivered
+	}
+
+	if cfg.pushHints {
+		if pusher, ok := w.(http.Pusher); ok {
+			hint := pollHintPath(topic, lastID)
+			if err := pusher.Push(hint, nil); err != nil {
+				log.Printf("Poll hint push failed for %s: %v", hint, err)
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
 
-	messages := broker.GetMessagesSince(sinceID, timeout)
+// handlePollHint serves the resource pushed by -push-hints. It only echoes
+// its parameters back as the next poll URL.
+func handlePollHint(w http.ResponseWriter, r *http.Request) {
+	since, err := strconv.Atoi(r.URL.Query().Get("since"))
+	if err != nil || since < 0 {
+		http.Error(w, "since must be a non-negative message ID", http.StatusBadRequest)
+		return
+	}
+	topic := topicParam(r)
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.Encode(map[string]interface{}{
+		"topic":      topic,
+		"next_since": since,
+		"poll_url":   "/poll?" + url.Values{"topic": {topic}, "since": {strconv.Itoa(since)}}.Encode(),
+	})
+}
+
+func handleAck(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	clientID := r.URL.Query().Get("client_id")
+	if clientID == "" {
+		http.Error(w, "client_id is required", http.StatusBadRequest)
+		return
+	}
+
+	upTo, err := strconv.Atoi(r.URL.Query().Get("up_to"))
+	if err != nil || upTo < 0 {
+		http.Error(w, "up_to must be a non-negative message ID", http.StatusBadRequest)
+		return
+	}
+
+	topic := topicParam(r)
+	acked := acks.Ack(clientID, topic, upTo)
+	log.Printf("Ack: client_id=%s, topic=%s, up_to=%d, acked=%d", clientID, topic, upTo, acked)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"messages": messages,
-		"count":    len(messages),
+		"client_id":   clientID,
+		"topic":       topic,
+		"acked_up_to": acked,
 	})
 }
 
-func handleSend(w http.ResponseWriter, r *http.Request) {
+// sendLimits bounds what /send and /send-bulk accept.
+type sendLimits struct {
+	maxBody int64 // request body, in bytes
+	maxText int   // each message text, in bytes
+}
+
+// decodeJSON strictly decodes a single JSON object from the request body
+// into dst. On failure it has already written a 413 (body over maxBody) or
+// 400 response.
+func decodeJSON(limits sendLimits, w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, limits.maxBody))
+	dec.DisallowUnknownFields()
+
+	err := dec.Decode(dst)
+	if err == nil && dec.More() {
+		err = errors.New("unexpected data after JSON object")
+	}
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			http.Error(w, fmt.Sprintf("Request body exceeds %d bytes", limits.maxBody), http.StatusRequestEntityTooLarge)
+			return false
+		}
+		http.Error(w, "Invalid JSON: "+err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func handleSend(limits sendLimits, w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
 	var req struct {
-		Text string `json:"text"`
+		Topic string `json:"topic"`
+		Text  string `json:"text"`
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+	if !decodeJSON(limits, w, r, &req) {
 		return
 	}
 
@@ -130,24 +690,221 @@ func handleSend(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Text is required", http.StatusBadRequest)
 		return
 	}
+	if len(req.Text) > limits.maxText {
+		http.Error(w, fmt.Sprintf("Text exceeds %d bytes", limits.maxText), http.StatusBadRequest)
+		return
+	}
 
-	msg := broker.AddMessage(req.Text)
-	log.Printf("New message: id=%d, text=%s", msg.ID, msg.Text)
+	if req.Topic == "" {
+		req.Topic = defaultTopic
+	}
+
+	msg := broker.AddMessage(req.Topic, req.Text)
+	log.Printf("New message: id=%d, topic=%s, text=%s", msg.ID, msg.Topic, msg.Text)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(msg)
 }
 
+func handleSendBulk(limits sendLimits, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Topic string   `json:"topic"`
+		Texts []string `json:"texts"`
+	}
+
+	if !decodeJSON(limits, w, r, &req) {
+		return
+	}
+
+	if len(req.Texts) == 0 {
+		http.Error(w, "Texts are required", http.StatusBadRequest)
+		return
+	}
+	// A larger batch would push its own first messages out of the topic
+	// before anyone could poll them, so the returned IDs would be dangling.
+	if len(req.Texts) > maxMessagesPerTopic {
+		http.Error(w, fmt.Sprintf("At most %d texts per batch", maxMessagesPerTopic), http.StatusBadRequest)
+		return
+	}
+	for _, text := range req.Texts {
+		if text == "" {
+			http.Error(w, "Texts must not be empty", http.StatusBadRequest)
+			return
+		}
+		if len(text) > limits.maxText {
+			http.Error(w, fmt.Sprintf("Text exceeds %d bytes", limits.maxText), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if req.Topic == "" {
+		req.Topic = defaultTopic
+	}
+
+	added := broker.AddMessages(req.Topic, req.Texts)
+	first, last := added[0].ID, added[len(added)-1].ID
+	log.Printf("Bulk messages: ids=%d-%d, topic=%s, count=%d", first, last, req.Topic, len(added))
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"topic":    req.Topic,
+		"first_id": first,
+		"last_id":  last,
+		"count":    len(added),
+	})
+}
+
+func handleNotify(limits sendLimits, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req struct {
+		Topic string `json:"topic"`
+		Type  string `json:"type"`
+		Data  string `json:"data"`
+	}
+
+	if !decodeJSON(limits, w, r, &req) {
+		return
+	}
+
+	if req.Type == "" {
+		http.Error(w, "Type is required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Data) > limits.maxText {
+		http.Error(w, fmt.Sprintf("Data exceeds %d bytes", limits.maxText), http.StatusBadRequest)
+		return
+	}
+
+	if req.Topic == "" {
+		req.Topic = defaultTopic
+	}
+
+	delivered := broker.Notify(req.Topic, req.Type, req.Data)
+	log.Printf("Event: topic=%s, type=%s, delivered to %d waiting pollers", req.Topic, req.Type, delivered)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"topic":     req.Topic,
+		"type":      req.Type,
+		"delivered": delivered,
+	})
+}
+
+// handleMessages lists a topic's stored messages in ID order. ?after= and
+// ?before= restrict it to IDs above/below a cursor, and ?limit= pages it:
+// with before= the page is the newest messages below the cursor and
+// next_cursor continues backwards (pass it as before=), otherwise the page
+// is the oldest messages and next_cursor continues forwards (as after=).
+// next_cursor is null on the last page.
 func handleMessages(w http.ResponseWriter, r *http.Request) {
-	messages := broker.GetAllMessages()
+	messages := broker.GetAllMessages(topicParam(r))
+
+	after, hasAfter := 0, false
+	if a, err := strconv.Atoi(r.URL.Query().Get("after")); err == nil {
+		after, hasAfter = a, true
+	}
+	before, hasBefore := 0, false
+	if b, err := strconv.Atoi(r.URL.Query().Get("before")); err == nil {
+		before, hasBefore = b, true
+	}
+	limit := 0
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = l
+	}
+
+	filtered := make([]Message, 0, len(messages))
+	for _, msg := range messages {
+		if (hasAfter && msg.ID <= after) || (hasBefore && msg.ID >= before) {
+			continue
+		}
+		filtered = append(filtered, msg)
+	}
+
+	page := filtered
+	var nextCursor interface{}
+	if limit > 0 && len(filtered) > limit {
+		if hasBefore && !hasAfter {
+			page = filtered[len(filtered)-limit:]
+			nextCursor = page[0].ID
+		} else {
+			page = filtered[:limit]
+			nextCursor = page[len(page)-1].ID
+		}
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"messages": messages,
-		"count":    len(messages),
+		"messages":    page,
+		"count":       len(page),
+		"next_cursor": nextCursor,
 	})
 }
 
+func handleClear(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	removed := broker.Clear()
+	acks.Reset()
+	log.Printf("Cleared %d messages", removed)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"removed": removed,
+	})
+}
+
+func handleStats(w http.ResponseWriter, r *http.Request) {
+	avgMs, p95Ms := stats.holdSummary()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"waiting_pollers": stats.waiting.Load(),
+		"polls_served":    stats.served.Load(),
+		"polls_abandoned": stats.abandoned.Load(),
+		"hold_avg_ms":     math.Round(avgMs*10) / 10,
+		"hold_p95_ms":     p95Ms,
+		"messages_sent":   broker.TotalAdded(),
+	})
+}
+
+// serverConfig is served on /config: hold and TTL limits, body caps, and
+// whether the background message generator and POST /clear are enabled.
+type serverConfig struct {
+	Server       string `json:"server"`
+	Addr         string `json:"addr"`
+	TLS          bool   `json:"tls"`
+	HTTP2        bool   `json:"http2"`
+	AutoGen      bool   `json:"autogen"`
+	MessageTTLMs int64  `json:"message_ttl_ms"`
+	MaxHoldMs    int64  `json:"max_hold_ms"`
+	MaxBody      int64  `json:"max_body"`
+	MaxText      int    `json:"max_text"`
+	PushHints    bool   `json:"push_hints"`
+	AllowClear   bool   `json:"allow_clear"`
+}
+
+func handleConfig(cfg serverConfig, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cfg)
+}
+
 func handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -383,7 +1140,7 @@ func autoMessageGenerator(broker *MessageBroker) {
 	index := 0
 	for range ticker.C {
 		msg := messages[index%len(messages)]
-		broker.AddMessage(msg)
+		broker.AddMessage(defaultTopic, msg)
 		log.Printf("Auto-generated message: %s", msg)
 		index++
 	}
@@ -392,24 +1149,81 @@ func autoMessageGenerator(broker *MessageBroker) {
 func main() {
 	addr := flag.String("addr", ":8080", "HTTP service address")
 	autoGen := flag.Bool("autogen", true, "Enable auto-message generation")
+	messageTTL := flag.Duration("message-ttl", 0, "Expire messages older than this (0 = keep until the per-topic cap)")
+	maxHold := flag.Duration("max-hold", 0, "Cap how long /poll holds a request, regardless of its timeout (0 = no cap)")
+	maxBody := flag.Int64("max-body", 1<<20, "Maximum request body size for /send and /send-bulk, in bytes")
+	maxText := flag.Int("max-text", 4096, "Maximum length of a message text, in bytes")
+	pushHints := flag.Bool("push-hints", false, "Push a /poll-hint resource with the next since value after each poll (HTTP/2 only)")
+	tlsCert := flag.String("cert", "", "TLS certificate file (enables HTTPS and HTTP/2)")
+	tlsKey := flag.String("key", "", "TLS key file")
+	allowClear := flag.Bool("allow-clear", false, "Expose POST /clear to delete all messages (don't enable on shared deployments)")
 	flag.Parse()
 
-	broker = NewMessageBroker()
+	broker = NewMessageBroker(*messageTTL)
+	if *messageTTL > 0 {
+		go broker.runSweeper(max(*messageTTL/10, time.Second))
+	}
 
 	if *autoGen {
 		go autoMessageGenerator(broker)
 	}
 
-	http.HandleFunc("/poll", handlePoll)
-	http.HandleFunc("/send", handleSend)
+	cfg := pollConfig{maxHold: *maxHold, pushHints: *pushHints}
+	http.HandleFunc("/poll", func(w http.ResponseWriter, r *http.Request) {
+		handlePoll(cfg, w, r)
+	})
+	http.HandleFunc("/poll-hint", handlePollHint)
+	limits := sendLimits{maxBody: *maxBody, maxText: *maxText}
+	http.HandleFunc("/send", func(w http.ResponseWriter, r *http.Request) {
+		handleSend(limits, w, r)
+	})
+	http.HandleFunc("/send-bulk", func(w http.ResponseWriter, r *http.Request) {
+		handleSendBulk(limits, w, r)
+	})
+	http.HandleFunc("/notify", func(w http.ResponseWriter, r *http.Request) {
+		handleNotify(limits, w, r)
+	})
 	http.HandleFunc("/messages", handleMessages)
+	http.HandleFunc("/ack", handleAck)
+	http.HandleFunc("/stats", handleStats)
+	// HTTP/2 (and with it push hints) is only negotiated over TLS.
+	tlsEnabled := *tlsCert != "" && *tlsKey != ""
+	serverCfg := serverConfig{
+		Server:       "long-polling",
+		Addr:         *addr,
+		TLS:          tlsEnabled,
+		HTTP2:        tlsEnabled,
+		AutoGen:      *autoGen,
+		MessageTTLMs: messageTTL.Milliseconds(),
+		MaxHoldMs:    maxHold.Milliseconds(),
+		MaxBody:      *maxBody,
+		MaxText:      *maxText,
+		PushHints:    *pushHints && tlsEnabled,
+		AllowClear:   *allowClear,
+	}
+	http.HandleFunc("/config", func(w http.ResponseWriter, r *http.Request) {
+		handleConfig(serverCfg, w, r)
+	})
 	http.HandleFunc("/health", handleHealth)
+	// The broker is created before the listener opens, so ready means live.
+	http.HandleFunc("/livez", handleHealth)
+	http.HandleFunc("/readyz", handleHealth)
+	if *allowClear {
+		http.HandleFunc("/clear", handleClear)
+	}
 
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/html")
 		w.Write([]byte(clientHTML))
 	})
 
-	log.Printf("Starting long-polling server on %s (auto-gen: %v)", *addr, *autoGen)
+	if tlsEnabled {
+		log.Printf("Starting HTTPS long-polling server on %s (auto-gen: %v, message-ttl: %s, push-hints: %v)", *addr, *autoGen, *messageTTL, *pushHints)
+		log.Fatal(http.ListenAndServeTLS(*addr, *tlsCert, *tlsKey, nil))
+	}
+	if *pushHints {
+		log.Printf("-push-hints needs HTTP/2, which this server only offers with -cert and -key")
+	}
+	log.Printf("Starting long-polling server on %s (auto-gen: %v, message-ttl: %s)", *addr, *autoGen, *messageTTL)
 	log.Fatal(http.ListenAndServe(*addr, nil))
 }
@@ -1,65 +1,465 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
 	"log"
+	"math/rand"
 	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// historySize is how many recent events are kept for Last-Event-ID replay.
+const historySize = 100
+
+// Event is a broadcast message. IDs increase monotonically from 1 and are
+// sent as the SSE id: field. An empty Type is sent without an event: line,
+// which EventSource delivers as "message".
+type Event struct {
+	ID   uint64
+	Type string
+	Data string
+	// JSON marks Data as already JSON-encoded rather than plain text.
+	JSON bool
+}
+
+// payload is the data: field of published events. Seq numbers broadcasts
+// globally from 1 so clients can spot gaps; it is left out of unicast
+// events, which are not part of that sequence.
+type payload struct {
+	Seq  uint64          `json:"seq,omitempty"`
+	Data json.RawMessage `json:"data"`
+}
+
+// wrap replaces ev.Data with its payload envelope.
+func (ev Event) wrap(seq uint64) Event {
+	data := json.RawMessage(ev.Data)
+	if !ev.JSON {
+		data, _ = json.Marshal(ev.Data)
+	}
+	b, _ := json.Marshal(payload{Seq: seq, Data: data})
+	ev.Data = string(b)
+	ev.JSON = true
+	return ev
+}
+
+// eventType returns the type a client sees ev as.
+func (ev Event) eventType() string {
+	if ev.Type == "" {
+		return "message"
+	}
+	return ev.Type
+}
+
+// client is a connected subscriber. A nil types set means every event type.
+type client struct {
+	events chan Event
+	types  map[string]bool
+	addr   string
+
+	// Set by the broker at registration.
+	id          uint64
+	connectedAt time.Time
+
+	// dropped counts events discarded because events was full.
+	dropped atomic.Uint64
+}
+
+func (c *client) wants(ev Event) bool {
+	return c.types == nil || c.types[ev.eventType()]
+}
+
+// registration asks the broker to add a client. Events after lastEventID
+// that are still in the history are sent back on replay before the client
+// starts receiving live events, so nothing is missed or duplicated.
+type registration struct {
+	client      *client
+	lastEventID uint64
+	replay      chan []Event
+}
+
+// Overflow policies for a client whose send queue is full.
+const (
+	overflowDropNewest = "drop-newest" // discard the event being sent
+	overflowDropOldest = "drop-oldest" // discard the oldest queued event
+	overflowDisconnect = "disconnect"  // close the client's stream
 )
 
+// brokerConfig controls per-client queueing.
+type brokerConfig struct {
+	// bufferSize is the capacity of each client's send queue.
+	bufferSize int
+	// overflow is one of the overflow* policies.
+	overflow string
+	// maxDrops disconnects a client once it has dropped more than this
+	// many events; 0 keeps slow clients connected regardless.
+	maxDrops uint64
+}
+
+// unicast asks the broker to send ev to a single client. result receives
+// the assigned event ID, or 0 if no client has that ID.
+type unicast struct {
+	clientID uint64
+	ev       Event
+	result   chan uint64
+}
+
 type Broker struct {
-	clients    map[chan string]bool
-	register   chan chan string
-	unregister chan chan string
-	broadcast  chan string
+	clients    map[*client]bool
+	byID       map[uint64]*client
+	register   chan registration
+	unregister chan *client
+	broadcast  chan Event
+	send       chan unicast
+	shutdown   chan chan struct{}
 	mu         sync.RWMutex
+	cfg        brokerConfig
+
+	// broadcasts counts every event published and is the source of each
+	// broadcast's seq.
+	broadcasts atomic.Uint64
+	// ready is set while run is accepting clients, for /readyz.
+	ready atomic.Bool
+
+	// Only touched by run.
+	nextID       uint64
+	nextClientID uint64
+	history      []Event
+	closed       bool
 }
 
-func newBroker() *Broker {
+func newBroker(cfg brokerConfig) *Broker {
 	return &Broker{
-		clients:    make(map[chan string]bool),
-		register:   make(chan chan string),
-		unregister: make(chan chan string),
-		broadcast:  make(chan string),
+		cfg:          cfg,
+		clients:      make(map[*client]bool),
+		byID:         make(map[uint64]*client),
+		register:     make(chan registration),
+		unregister:   make(chan *client),
+		broadcast:    make(chan Event),
+		send:         make(chan unicast),
+		shutdown:     make(chan chan struct{}),
+		nextID:       1,
+		nextClientID: 1,
 	}
 }
 
+// missedSince returns the buffered events newer than lastEventID that c
+// subscribed to.
+func (b *Broker) missedSince(c *client, lastEventID uint64) []Event {
+	var missed []Event
+	for _, ev := range b.history {
+		if ev.ID > lastEventID && c.wants(ev) {
+			missed = append(missed, ev)
+		}
+	}
+	return missed
+}
+
 func (b *Broker) run() {
+	b.ready.Store(true)
 	for {
 		select {
-		case client := <-b.register:
+		case reg := <-b.register:
+			reg.client.id = b.nextClientID
+			reg.client.connectedAt = time.Now()
+			b.nextClientID++
+			if b.closed {
+				// Streams opened during shutdown end right after the
+				// connected event.
+				reg.client.events <- shutdownEvent
+				close(reg.client.events)
+				reg.replay <- nil
+				continue
+			}
 			b.mu.Lock()
-			b.clients[client] = true
+			b.clients[reg.client] = true
+			b.byID[reg.client.id] = reg.client
 			count := len(b.clients)
 			b.mu.Unlock()
-			log.Printf("Client connected. Total: %d", count)
+			var missed []Event
+			if reg.lastEventID > 0 {
+				missed = b.missedSince(reg.client, reg.lastEventID)
+			}
+			reg.replay <- missed
+			log.Printf("Client %d (%s) connected (last-event-id=%d, replaying %d, types=%s). Total: %d", reg.client.id, reg.client.addr, reg.lastEventID, len(missed), reg.client.typeList(), count)
 
-		case client := <-b.unregister:
+		case c := <-b.unregister:
 			b.mu.Lock()
-			if _, ok := b.clients[client]; ok {
-				delete(b.clients, client)
-				close(client)
+			_, ok := b.clients[c]
+			if ok {
+				delete(b.clients, c)
+				delete(b.byID, c.id)
+				close(c.events)
 			}
 			count := len(b.clients)
 			b.mu.Unlock()
-			log.Printf("Client disconnected. Total: %d", count)
+			if ok {
+				log.Printf("Client %d disconnected (dropped %d). Total: %d", c.id, c.dropped.Load(), count)
+			}
+
+		case ev := <-b.broadcast:
+			ev = ev.wrap(b.broadcasts.Add(1))
+			ev.ID = b.nextID
+			b.nextID++
+			b.history = append(b.history, ev)
+			if len(b.history) > historySize {
+				b.history = b.history[len(b.history)-historySize:]
+			}
+
+			var slow []*client
+			b.mu.RLock()
+			for c := range b.clients {
+				if !c.wants(ev) {
+					continue
+				}
+				if !b.deliver(c, ev) {
+					slow = append(slow, c)
+				}
+			}
+			b.mu.RUnlock()
+			b.disconnect(slow)
 
-		case msg := <-b.broadcast:
+		case u := <-b.send:
+			// Unicast events share the ID sequence so each stream's IDs
+			// stay increasing, but are kept out of the replay history,
+			// which every client may read.
 			b.mu.RLock()
-			for client := range b.clients {
+			c := b.byID[u.clientID]
+			b.mu.RUnlock()
+			if c == nil {
+				u.result <- 0
+				continue
+			}
+			ev := u.ev.wrap(0)
+			ev.ID = b.nextID
+			b.nextID++
+			if !b.deliver(c, ev) {
+				b.disconnect([]*client{c})
+			}
+			u.result <- ev.ID
+
+		case done := <-b.shutdown:
+			b.closed = true
+			b.ready.Store(false)
+			b.mu.Lock()
+			count := len(b.clients)
+			for c := range b.clients {
+				// Make room so the shutdown event is never the one dropped.
 				select {
-				case client <- msg:
+				case c.events <- shutdownEvent:
 				default:
+					select {
+					case <-c.events:
+					default:
+					}
+					c.events <- shutdownEvent
 				}
+				close(c.events)
 			}
-			b.mu.RUnlock()
+			b.clients = make(map[*client]bool)
+			b.byID = make(map[uint64]*client)
+			b.mu.Unlock()
+			log.Printf("Sent shutdown event to %d clients", count)
+			close(done)
 		}
 	}
 }
 
-func handleSSE(broker *Broker, w http.ResponseWriter, r *http.Request) {
+// shutdownEvent is the last event every client receives before the server
+// closes its stream. It has no ID, so a reconnecting EventSource still
+// resumes from the last real event.
+var shutdownEvent = Event{Type: "shutdown", Data: `{"reason":"server shutting down"}`}
+
+// Shutdown queues shutdownEvent for every client and closes their streams.
+// Handlers write out what is queued and return, which lets
+// http.Server.Shutdown finish.
+func (b *Broker) Shutdown() {
+	done := make(chan struct{})
+	b.shutdown <- done
+	<-done
+}
+
+// disconnect removes clients that overflowed. Closing events makes the
+// handler return; its own unregister then finds the client already gone.
+func (b *Broker) disconnect(slow []*client) {
+	if len(slow) == 0 {
+		return
+	}
+	b.mu.Lock()
+	for _, c := range slow {
+		delete(b.clients, c)
+		delete(b.byID, c.id)
+		close(c.events)
+	}
+	count := len(b.clients)
+	b.mu.Unlock()
+	for _, c := range slow {
+		log.Printf("Client %d disconnected after %d dropped events. Total: %d", c.id, c.dropped.Load(), count)
+	}
+}
+
+// clientStats is one entry in /stats.
+type clientStats struct {
+	ID          uint64    `json:"id"`
+	RemoteAddr  string    `json:"remote_addr"`
+	Types       string    `json:"types"`
+	ConnectedAt time.Time `json:"connected_at"`
+	AgeMs       int64     `json:"age_ms"`
+	Dropped     uint64    `json:"dropped"`
+	Queued      int       `json:"queued"`
+}
+
+// Stats is the /stats response.
+type Stats struct {
+	Clients         int           `json:"clients"`
+	EventsBroadcast uint64        `json:"events_broadcast"`
+	HighestSeq      uint64        `json:"highest_seq"`
+	ClientStats     []clientStats `json:"client_stats"`
+}
+
+func (b *Broker) stats() Stats {
+	now := time.Now()
+	b.mu.RLock()
+	s := Stats{
+		Clients:         len(b.clients),
+		EventsBroadcast: b.broadcasts.Load(),
+		HighestSeq:      b.broadcasts.Load(),
+		ClientStats:     make([]clientStats, 0, len(b.clients)),
+	}
+	for c := range b.clients {
+		s.ClientStats = append(s.ClientStats, clientStats{
+			ID:          c.id,
+			RemoteAddr:  c.addr,
+			Types:       c.typeList(),
+			ConnectedAt: c.connectedAt,
+			AgeMs:       now.Sub(c.connectedAt).Milliseconds(),
+			Dropped:     c.dropped.Load(),
+			Queued:      len(c.events),
+		})
+	}
+	b.mu.RUnlock()
+	sort.Slice(s.ClientStats, func(i, j int) bool { return s.ClientStats[i].ID < s.ClientStats[j].ID })
+	return s
+}
+
+// deliver queues ev for c, applying the overflow policy if the queue is
+// full. It reports false if c should be disconnected.
+func (b *Broker) deliver(c *client, ev Event) bool {
+	select {
+	case c.events <- ev:
+		return true
+	default:
+	}
+
+	dropped := c.dropped.Add(1)
+	switch b.cfg.overflow {
+	case overflowDisconnect:
+		log.Printf("Client %d too slow, queue full at event %d", c.id, ev.ID)
+		return false
+	case overflowDropOldest:
+		// Only run sends on events, so after taking one out there is room
+		// unless the handler raced us and emptied it first, which is fine.
+		var oldest Event
+		select {
+		case oldest = <-c.events:
+		default:
+		}
+		select {
+		case c.events <- ev:
+		default:
+		}
+		log.Printf("Client %d too slow, dropped oldest event %d (%d dropped so far)", c.id, oldest.ID, dropped)
+	default:
+		log.Printf("Client %d too slow, dropped event %d (%d dropped so far)", c.id, ev.ID, dropped)
+	}
+	return b.cfg.maxDrops == 0 || dropped <= b.cfg.maxDrops
+}
+
+// typeList returns the client's filter for logging.
+func (c *client) typeList() string {
+	if c.types == nil {
+		return "*"
+	}
+	types := make([]string, 0, len(c.types))
+	for t := range c.types {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return strings.Join(types, ",")
+}
+
+// parseTypes turns "alerts,status" into a filter set; empty means all types.
+func parseTypes(s string) map[string]bool {
+	var types map[string]bool
+	for _, t := range strings.Split(s, ",") {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		if types == nil {
+			types = make(map[string]bool)
+		}
+		types[t] = true
+	}
+	return types
+}
+
+var lineEndings = strings.NewReplacer("\r\n", "\n", "\r", "\n")
+
+// validEventType reports whether t can go on an event: line. A CR or LF
+// would end the line and let the rest inject arbitrary fields (including
+// id:, which would corrupt every client's Last-Event-ID).
+func validEventType(t string) bool {
+	return !strings.ContainsAny(t, "\r\n")
+}
+
+// writeEvent writes ev in SSE framing, splitting multi-line data into
+// several data: lines as the format requires. An ID of 0 is omitted.
+func writeEvent(w io.Writer, ev Event) error {
+	var sb strings.Builder
+	if ev.ID != 0 {
+		fmt.Fprintf(&sb, "id: %d\n", ev.ID)
+	}
+	if ev.Type != "" {
+		fmt.Fprintf(&sb, "event: %s\n", ev.Type)
+	}
+	// CR and CRLF also end a line in the event stream, so split on them too
+	// rather than let a bare CR smuggle in a field of its own.
+	for _, line := range strings.Split(lineEndings.Replace(ev.Data), "\n") {
+		fmt.Fprintf(&sb, "data: %s\n", line)
+	}
+	sb.WriteString("\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// sseConfig holds the per-connection stream settings from flags.
+type sseConfig struct {
+	// heartbeat is how long a stream may sit idle before a comment line is
+	// written to keep proxies from timing it out; 0 disables heartbeats.
+	heartbeat time.Duration
+	// retryMs is sent as a retry: directive before the connected event to
+	// set the client's reconnection delay; 0 leaves the browser default.
+	retryMs int
+}
+
+func handleSSE(broker *Broker, cfg sseConfig, w http.ResponseWriter, r *http.Request) {
 	flusher, ok := w.(http.Flusher)
 	if !ok {
 		http.Error(w, "SSE not supported", http.StatusInternalServerError)
@@ -68,33 +468,236 @@ func handleSSE(broker *Broker, w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Content-Type", "text/event-stream")
 	w.Header().Set("Cache-Control", "no-cache")
-	w.Header().Set("Connection", "keep-alive")
-	w.Header().Set("Access-Control-Allow-Origin", "*")
+	// Connection is a hop-by-hop header that HTTP/2 forbids.
+	if r.ProtoMajor == 1 {
+		w.Header().Set("Connection", "keep-alive")
+	}
 
-	client := make(chan string, 10)
-	broker.register <- client
+	// EventSource sends Last-Event-ID when it reconnects; the query
+	// parameter lets non-browser clients do the same.
+	lastEventID, _ := strconv.ParseUint(r.Header.Get("Last-Event-ID"), 10, 64)
+	if v := r.URL.Query().Get("lastEventId"); v != "" {
+		lastEventID, _ = strconv.ParseUint(v, 10, 64)
+	}
+
+	// ?types=alerts,status limits the stream to those event types;
+	// untyped broadcasts are matched as "message".
+	c := &client{
+		events: make(chan Event, broker.cfg.bufferSize),
+		types:  parseTypes(r.URL.Query().Get("types")),
+		addr:   r.RemoteAddr,
+	}
+	replay := make(chan []Event, 1)
+	broker.register <- registration{client: c, lastEventID: lastEventID, replay: replay}
 
 	defer func() {
-		broker.unregister <- client
+		broker.unregister <- c
 	}()
 
 	notify := r.Context().Done()
 
-	fmt.Fprintf(w, "event: connected\ndata: {\"status\":\"connected\"}\n\n")
+	if cfg.retryMs > 0 {
+		fmt.Fprintf(w, "retry: %d\n\n", cfg.retryMs)
+	}
+	// The broker assigns the client ID during registration, so it is set
+	// once the replay has been received.
+	missed := <-replay
+	fmt.Fprintf(w, "event: connected\ndata: {\"status\":\"connected\",\"client_id\":%d,\"proto\":%q}\n\n", c.id, r.Proto)
+	for _, ev := range missed {
+		if err := writeEvent(w, ev); err != nil {
+			return
+		}
+	}
 	flusher.Flush()
 
+	// The heartbeat timer restarts after every write, so comments only go
+	// out while the stream is otherwise idle. EventSource ignores comment
+	// lines, so they never surface as message events.
+	var heartbeat <-chan time.Time
+	var timer *time.Timer
+	if cfg.heartbeat > 0 {
+		timer = time.NewTimer(cfg.heartbeat)
+		defer timer.Stop()
+		heartbeat = timer.C
+	}
+
 	for {
 		select {
 		case <-notify:
 			return
-		case msg, ok := <-client:
+		case <-heartbeat:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+			timer.Reset(cfg.heartbeat)
+		case ev, ok := <-c.events:
 			if !ok {
 				return
 			}
-			fmt.Fprintf(w, "data: %s\n\n", msg)
+			if err := writeEvent(w, ev); err != nil {
+				return
+			}
 			flusher.Flush()
+			if timer != nil {
+				if !timer.Stop() {
+					<-timer.C
+				}
+				timer.Reset(cfg.heartbeat)
+			}
+		}
+	}
+}
+
+// Payload shapes for -tick-payload.
+const (
+	tickText    = "text"    // plain "Tick at <time>" message
+	tickJSON    = "json"    // nested object with an items array
+	tickCounter = "counter" // small object with a running count
+	tickRandom  = "random"  // random alphanumeric string
+)
+
+// tickItem is one element of a json tick payload's items array.
+type tickItem struct {
+	Index int     `json:"index"`
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+}
+
+// tickEvent builds the count-th auto-broadcast in the given shape. size is
+// the approximate length of the encoded data in bytes; 0 keeps each shape
+// at its natural size. Every JSON shape names itself in "payload".
+func tickEvent(kind string, size int, count uint64, t time.Time) Event {
+	var v any
+	switch kind {
+	case tickJSON:
+		obj := struct {
+			Payload string     `json:"payload"`
+			Count   uint64     `json:"count"`
+			Time    string     `json:"time"`
+			Items   []tickItem `json:"items"`
+		}{Payload: tickJSON, Count: count, Time: t.Format(time.RFC3339Nano), Items: []tickItem{}}
+		// Each item encodes to roughly 50 bytes.
+		for i := 0; i == 0 || (size > 0 && 100+50*i < size); i++ {
+			obj.Items = append(obj.Items, tickItem{Index: i, Name: fmt.Sprintf("item-%d", i), Value: rand.Float64()})
+		}
+		v = obj
+	case tickCounter:
+		obj := struct {
+			Payload string `json:"payload"`
+			Count   uint64 `json:"count"`
+			Pad     string `json:"pad,omitempty"`
+		}{Payload: tickCounter, Count: count}
+		if n := size - 50; n > 0 {
+			obj.Pad = strings.Repeat("x", n)
+		}
+		v = obj
+	case tickRandom:
+		n := size
+		if n <= 0 {
+			n = 32
 		}
+		v = struct {
+			Payload string `json:"payload"`
+			Size    int    `json:"size"`
+			Data    string `json:"data"`
+		}{Payload: tickRandom, Size: n, Data: randomString(n)}
+	default:
+		return Event{Data: fmt.Sprintf("Tick at %s", t.Format(time.RFC3339))}
 	}
+	b, _ := json.Marshal(v)
+	return Event{Data: string(b), JSON: true}
+}
+
+const alphanumeric = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+func randomString(n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = alphanumeric[rand.Intn(len(alphanumeric))]
+	}
+	return string(b)
+}
+
+// corsMaxAge is how long browsers may cache a preflight result, in seconds.
+const corsMaxAge = "600"
+
+// withCORS adds CORS headers for origin, which is "*" or a comma-separated
+// list of allowed origins, and answers preflight requests itself. With a
+// list, the request's Origin is echoed back only if it is allowed.
+func withCORS(origin, methods string, next http.HandlerFunc) http.HandlerFunc {
+	allowed := make(map[string]bool)
+	for _, o := range strings.Split(origin, ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			allowed[o] = true
+		}
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		reqOrigin := r.Header.Get("Origin")
+		h := w.Header()
+		switch {
+		case allowed["*"]:
+			h.Set("Access-Control-Allow-Origin", "*")
+		case reqOrigin != "" && allowed[reqOrigin]:
+			h.Set("Access-Control-Allow-Origin", reqOrigin)
+			h.Set("Access-Control-Allow-Credentials", "true")
+			h.Add("Vary", "Origin")
+		default:
+			h.Add("Vary", "Origin")
+		}
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			h.Set("Access-Control-Allow-Methods", methods+", OPTIONS")
+			h.Set("Access-Control-Allow-Headers", "Content-Type, Last-Event-ID, Cache-Control")
+			h.Set("Access-Control-Max-Age", corsMaxAge)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// serverConfig is served on /config. HTTP2 is true only when TLS is on
+// and h2 is offered; the tick, heartbeat and overflow fields describe what
+// a subscriber should expect on the stream.
+type serverConfig struct {
+	Server         string `json:"server"`
+	Addr           string `json:"addr"`
+	TLS            bool   `json:"tls"`
+	HTTP2          bool   `json:"http2"`
+	TickMs         int64  `json:"tick_ms"`
+	TickPayload    string `json:"tick_payload"`
+	TickSize       int    `json:"tick_size"`
+	HeartbeatMs    int64  `json:"heartbeat_ms"`
+	RetryMs        int    `json:"retry_ms"`
+	CORSOrigin     string `json:"cors_origin"`
+	DrainTimeoutMs int64  `json:"drain_timeout_ms"`
+	ClientBuffer   int    `json:"client_buffer"`
+	Overflow       string `json:"overflow"`
+	MaxDrops       uint64 `json:"max_drops"`
+}
+
+func handleConfig(cfg serverConfig, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cfg)
+}
+
+// handleReadyz reports whether the broker is accepting clients: not before
+// it starts, and not once shutdown has begun. /livez only says the process
+// is up.
+func handleReadyz(broker *Broker, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if !broker.ready.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"status":"unavailable","reason":"broker not running"}`))
+		return
+	}
+	w.Write([]byte(`{"status":"ok"}`))
 }
 
 func handleHealth(w http.ResponseWriter, r *http.Request) {
@@ -103,12 +706,103 @@ func handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(`{"status":"ok"}`))
 }
 
+// maxBroadcastBody caps POST /broadcast bodies.
+const maxBroadcastBody = 1 << 20
+
+// broadcastRequest is the POST /broadcast and /send/{clientID} body. Data may be any JSON value
+// and is sent compacted as the event's data: field.
+type broadcastRequest struct {
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data"`
+}
+
+func handleStats(broker *Broker, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(broker.stats())
+}
+
+// decodeEvent reads a broadcastRequest body into an Event, writing an
+// error response and returning false if it is invalid.
+func decodeEvent(w http.ResponseWriter, r *http.Request) (Event, bool) {
+	var req broadcastRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBroadcastBody)).Decode(&req); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			http.Error(w, "Request body too large", http.StatusRequestEntityTooLarge)
+			return Event{}, false
+		}
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return Event{}, false
+	}
+	if len(req.Data) == 0 {
+		http.Error(w, "Missing data", http.StatusBadRequest)
+		return Event{}, false
+	}
+	if !validEventType(req.Event) {
+		http.Error(w, "Event type must not contain CR or LF", http.StatusBadRequest)
+		return Event{}, false
+	}
+	var data bytes.Buffer
+	if err := json.Compact(&data, req.Data); err != nil {
+		http.Error(w, "Invalid JSON body", http.StatusBadRequest)
+		return Event{}, false
+	}
+	return Event{Type: req.Event, Data: data.String(), JSON: true}, true
+}
+
+// handleSend delivers a POST /send/{clientID} body to one client only.
+func handleSend(broker *Broker, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	clientID, err := strconv.ParseUint(strings.TrimPrefix(r.URL.Path, "/send/"), 10, 64)
+	if err != nil || clientID == 0 {
+		http.Error(w, "Invalid client ID", http.StatusBadRequest)
+		return
+	}
+	ev, ok := decodeEvent(w, r)
+	if !ok {
+		return
+	}
+
+	result := make(chan uint64, 1)
+	broker.send <- unicast{clientID: clientID, ev: ev, result: result}
+	id := <-result
+	if id == 0 {
+		http.Error(w, "Client not found", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"status":"sent","client_id":%d,"id":%d}`, clientID, id)
+}
+
 func handleBroadcast(broker *Broker, w http.ResponseWriter, r *http.Request) {
-	msg := r.URL.Query().Get("msg")
-	if msg == "" {
-		msg = fmt.Sprintf("Broadcast at %s", time.Now().Format(time.RFC3339))
+	var ev Event
+	switch r.Method {
+	case http.MethodPost:
+		var ok bool
+		if ev, ok = decodeEvent(w, r); !ok {
+			return
+		}
+	case http.MethodGet:
+		// The query form predates POST and is kept for simple curl tests.
+		msg := r.URL.Query().Get("msg")
+		if msg == "" {
+			msg = fmt.Sprintf("Broadcast at %s", time.Now().Format(time.RFC3339))
+		}
+		ev = Event{Type: r.URL.Query().Get("event"), Data: msg}
+		if !validEventType(ev.Type) {
+			http.Error(w, "Event type must not contain CR or LF", http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
-	broker.broadcast <- msg
+	broker.broadcast <- ev
 	w.Header().Set("Content-Type", "application/json")
 	w.Write([]byte(`{"status":"sent"}`))
 }
@@ -196,7 +890,7 @@ const clientHTML = `<!DOCTYPE html>
                 };
 
                 eventSource.onmessage = function(e) {
-                    log('← ' + e.data, 'event');
+                    log('← #' + e.lastEventId + ' ' + e.data, 'event');
                 };
 
                 eventSource.addEventListener('connected', function(e) {
@@ -225,8 +919,12 @@ const clientHTML = `<!DOCTYPE html>
         }
 
         function broadcast() {
-            const msg = encodeURIComponent(document.getElementById('message').value);
-            fetch('/broadcast?msg=' + msg)
+            const msg = document.getElementById('message').value;
+            fetch('/broadcast', {
+                method: 'POST',
+                headers: { 'Content-Type': 'application/json' },
+                body: JSON.stringify({ data: msg })
+            })
                 .then(r => r.json())
                 .then(data => log('Broadcast sent', 'system'))
                 .catch(e => log('Broadcast failed: ' + e.message, 'error'));
@@ -240,40 +938,142 @@ func main() {
 	tlsCert := flag.String("cert", "", "TLS certificate file")
 	tlsKey := flag.String("key", "", "TLS key file")
 	autoTick := flag.Duration("tick", 0, "Auto-broadcast interval (e.g., 5s)")
+	tickPayload := flag.String("tick-payload", tickText, "Auto-broadcast payload shape: text, json, counter or random")
+	tickSize := flag.Int("tick-size", 0, "Approximate auto-broadcast data size in bytes for json, counter and random (0 = natural size)")
+	heartbeat := flag.Duration("heartbeat", 0, "Idle interval between keep-alive comments (e.g., 15s), 0 to disable")
+	retryMs := flag.Int("retry-ms", 0, "Reconnection delay sent to clients as a retry: directive, 0 to omit")
+	enableH2 := flag.Bool("http2", true, "Offer HTTP/2 (h2) on TLS connections; false pins them to HTTP/1.1")
+	corsOrigin := flag.String("cors-origin", "*", "Allowed CORS origin: * or a comma-separated list of origins")
+	drainTimeout := flag.Duration("drain-timeout", 5*time.Second, "How long to wait for streams to flush the shutdown event before closing connections")
+	maxDrops := flag.Uint64("max-drops", 0, "Disconnect clients that drop more than this many events, 0 to never disconnect")
+	bufferSize := flag.Int("client-buffer", 10, "Per-client send queue size")
+	overflow := flag.String("overflow", overflowDropNewest, "Policy when a client's queue is full: drop-newest, drop-oldest or disconnect")
 	flag.Parse()
 
-	broker := newBroker()
+	switch *tickPayload {
+	case tickText, tickJSON, tickCounter, tickRandom:
+	default:
+		log.Fatalf("Unknown -tick-payload %q", *tickPayload)
+	}
+
+	switch *overflow {
+	case overflowDropNewest, overflowDropOldest, overflowDisconnect:
+	default:
+		log.Fatalf("Unknown -overflow policy %q", *overflow)
+	}
+	if *bufferSize < 1 {
+		log.Fatalf("-client-buffer must be at least 1")
+	}
+
+	cfg := sseConfig{heartbeat: *heartbeat, retryMs: *retryMs}
+	tlsEnabled := *tlsCert != "" && *tlsKey != ""
+	serverCfg := serverConfig{
+		Server:         "sse",
+		Addr:           *addr,
+		TLS:            tlsEnabled,
+		HTTP2:          tlsEnabled && *enableH2,
+		TickMs:         autoTick.Milliseconds(),
+		TickPayload:    *tickPayload,
+		TickSize:       *tickSize,
+		HeartbeatMs:    heartbeat.Milliseconds(),
+		RetryMs:        *retryMs,
+		CORSOrigin:     *corsOrigin,
+		DrainTimeoutMs: drainTimeout.Milliseconds(),
+		ClientBuffer:   *bufferSize,
+		Overflow:       *overflow,
+		MaxDrops:       *maxDrops,
+	}
+
+	broker := newBroker(brokerConfig{bufferSize: *bufferSize, overflow: *overflow, maxDrops: *maxDrops})
 	go broker.run()
 
 	if *autoTick > 0 {
 		go func() {
 			ticker := time.NewTicker(*autoTick)
+			var count uint64
 			for t := range ticker.C {
-				broker.broadcast <- fmt.Sprintf("Tick at %s", t.Format(time.RFC3339))
+				count++
+				broker.broadcast <- tickEvent(*tickPayload, *tickSize, count, t)
 			}
 		}()
 	}
 
-	http.HandleFunc("/events", func(w http.ResponseWriter, r *http.Request) {
-		handleSSE(broker, w, r)
-	})
+	http.HandleFunc("/events", withCORS(*corsOrigin, "GET", func(w http.ResponseWriter, r *http.Request) {
+		handleSSE(broker, cfg, w, r)
+	}))
 
-	http.HandleFunc("/broadcast", func(w http.ResponseWriter, r *http.Request) {
+	http.HandleFunc("/broadcast", withCORS(*corsOrigin, "GET, POST", func(w http.ResponseWriter, r *http.Request) {
 		handleBroadcast(broker, w, r)
+	}))
+
+	http.HandleFunc("/send/", withCORS(*corsOrigin, "POST", func(w http.ResponseWriter, r *http.Request) {
+		handleSend(broker, w, r)
+	}))
+
+	http.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
+		handleStats(broker, w, r)
+	})
+
+	http.HandleFunc("/config", func(w http.ResponseWriter, r *http.Request) {
+		handleConfig(serverCfg, w, r)
 	})
 
 	http.HandleFunc("/health", handleHealth)
+	http.HandleFunc("/livez", handleHealth)
+	http.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		handleReadyz(broker, w, r)
+	})
 
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/html")
 		w.Write([]byte(clientHTML))
 	})
 
-	if *tlsCert != "" && *tlsKey != "" {
-		log.Printf("Starting SSE server (HTTPS) on %s", *addr)
-		log.Fatal(http.ListenAndServeTLS(*addr, *tlsCert, *tlsKey, nil))
-	} else {
-		log.Printf("Starting SSE server on %s", *addr)
-		log.Fatal(http.ListenAndServe(*addr, nil))
+	server := &http.Server{Addr: *addr}
+	if tlsEnabled && *enableH2 {
+		if err := http2.ConfigureServer(server, nil); err != nil {
+			log.Fatalf("Configuring HTTP/2: %v", err)
+		}
+	} else if tlsEnabled {
+		// -http2=false: an empty TLSNextProto stops net/http from
+		// negotiating h2, so TLS connections stay on HTTP/1.1.
+		server.TLSNextProto = make(map[string]func(*http.Server, *tls.Conn, http.Handler))
+	}
+	errc := make(chan error, 1)
+	go func() {
+		if tlsEnabled {
+			proto := "HTTP/1.1"
+			if *enableH2 {
+				proto = "h2"
+			}
+			log.Printf("Starting SSE server (HTTPS, %s) on %s", proto, *addr)
+			errc <- server.ListenAndServeTLS(*tlsCert, *tlsKey)
+		} else {
+			log.Printf("Starting SSE server on %s", *addr)
+			errc <- server.ListenAndServe()
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	select {
+	case err := <-errc:
+		log.Fatal(err)
+	case <-ctx.Done():
+	}
+	stop()
+
+	// Tell clients first so the shutdown event is on the wire before the
+	// server stops waiting for their handlers.
+	log.Printf("Shutting down, drain timeout %s", *drainTimeout)
+	broker.Shutdown()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), *drainTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Drain incomplete (%v), closing remaining connections", err)
+		server.Close()
 	}
+	log.Printf("Server stopped")
 }
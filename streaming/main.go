@@ -1,15 +1,232 @@
 package main
 
 import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"io"
 	"log"
+	"math"
 	"math/rand"
+	"net"
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
+
+	"golang.org/x/time/rate"
 )
 
+// fillOffsets writes the offset-counter pattern for body position offset
+// into buf: the body is a sequence of big-endian uint64s, each holding its own
+// byte offset, so a dropped, duplicated or reordered region shows up as a word
+// that doesn't match its position.
+func fillOffsets(buf []byte, offset int) {
+	for i := range buf {
+		pos := offset + i
+		word := uint64(pos - pos%8)
+		buf[i] = byte(word >> (56 - 8*(pos%8)))
+	}
+}
+
+// writeOffsets writes the first n bytes of the fillOffsets pattern to w in
+// fixed-size chunks, so the size of n doesn't drive memory use.
+func writeOffsets(w io.Writer, n int) (int, error) {
+	chunk := make([]byte, min(n, 8192))
+	written := 0
+	for written < n {
+		toSend := min(n-written, len(chunk))
+		fillOffsets(chunk[:toSend], written)
+		m, err := w.Write(chunk[:toSend])
+		written += m
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// parseRange interprets a Range header against a body of size bytes and
+// returns the selected span and the status to answer with. Only a single
+// "bytes=" range is honored; multiple ranges (which would need a
+// multipart/byteranges body) and malformed headers fall back to a full 200.
+func parseRange(header string, size int) (start, length, status int) {
+	spec, ok := strings.CutPrefix(header, "bytes=")
+	if header == "" || !ok || strings.Contains(spec, ",") {
+		return 0, size, http.StatusOK
+	}
+	first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+	if !ok {
+		return 0, size, http.StatusOK
+	}
+
+	if first == "" {
+		// Suffix range: the final N bytes.
+		n, err := strconv.Atoi(last)
+		if err != nil || n < 0 {
+			return 0, size, http.StatusOK
+		}
+		if n == 0 {
+			return 0, 0, http.StatusRequestedRangeNotSatisfiable
+		}
+		if n > size {
+			n = size
+		}
+		return size - n, n, http.StatusPartialContent
+	}
+
+	start, err := strconv.Atoi(first)
+	if err != nil || start < 0 {
+		return 0, size, http.StatusOK
+	}
+	end := size - 1
+	if last != "" {
+		e, err := strconv.Atoi(last)
+		if err != nil || e < start {
+			return 0, size, http.StatusOK
+		}
+		if e < end {
+			end = e
+		}
+	}
+	if start >= size {
+		return 0, 0, http.StatusRequestedRangeNotSatisfiable
+	}
+	return start, end - start + 1, http.StatusPartialContent
+}
+
+// writeThrottled writes p in pieces no larger than the limiter's burst,
+// waiting for tokens before each, so the overall rate holds regardless of
+// the caller's chunk size. A nil limiter writes p directly.
+func writeThrottled(ctx context.Context, w io.Writer, p []byte, limiter *rate.Limiter) (int, error) {
+	if limiter == nil {
+		return w.Write(p)
+	}
+	written := 0
+	for written < len(p) {
+		n := min(len(p)-written, limiter.Burst())
+		if err := limiter.WaitN(ctx, n); err != nil {
+			return written, err
+		}
+		m, err := w.Write(p[written : written+n])
+		written += m
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, nil
+}
+
+// streamProgress is shared between a /stream?id= download and any /progress
+// watchers for the same id.
+type streamProgress struct {
+	total int64
+	sent  atomic.Int64
+	done  atomic.Bool
+}
+
+// progressByID maps ids to *streamProgress. Entries outlive their stream by
+// progressRetention so a late watcher still sees the final state.
+var progressByID sync.Map
+
+const progressRetention = 30 * time.Second
+
+func trackProgress(id string, total int) *streamProgress {
+	p := &streamProgress{total: int64(total)}
+	progressByID.Store(id, p)
+	return p
+}
+
+func (p *streamProgress) finish(id string) {
+	p.done.Store(true)
+	time.AfterFunc(progressRetention, func() {
+		progressByID.CompareAndDelete(id, p)
+	})
+}
+
+type progressEvent struct {
+	Sent  int64   `json:"sent"`
+	Total int64   `json:"total"`
+	Pct   float64 `json:"pct"`
+	Done  bool    `json:"done"`
+}
+
+// handleProgress reports a /stream?id= download's progress as server-sent
+// events until it finishes. It can be opened before the download starts.
+func handleProgress(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	id := r.URL.Query().Get("id")
+	if id == "" {
+		http.Error(w, "id is required", http.StatusBadRequest)
+		return
+	}
+
+	interval := 250
+	if i, err := strconv.Atoi(r.URL.Query().Get("interval")); err == nil && i > 0 {
+		interval = i
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	log.Printf("Progress watcher: id=%s, interval=%dms", id, interval)
+
+	ticker := time.NewTicker(time.Duration(interval) * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		if v, ok := progressByID.Load(id); ok {
+			p := v.(*streamProgress)
+			done := p.done.Load()
+			sent := p.sent.Load()
+			pct := 100.0
+			if p.total > 0 {
+				pct = float64(sent) * 100 / float64(p.total)
+			}
+			data, _ := json.Marshal(progressEvent{
+				Sent:  sent,
+				Total: p.total,
+				Pct:   math.Round(pct*10) / 10,
+				Done:  done,
+			})
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+				log.Printf("Progress write error for id=%s: %v", id, err)
+				return
+			}
+			flusher.Flush()
+			if done {
+				log.Printf("Progress complete: id=%s, sent %d of %d bytes", id, sent, p.total)
+				return
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-r.Context().Done():
+			log.Printf("Progress watcher for id=%s disconnected", id)
+			return
+		}
+	}
+}
+
 func handleStream(w http.ResponseWriter, r *http.Request) {
 	flusher, ok := w.(http.Flusher)
 	if !ok {
@@ -41,38 +258,212 @@ func handleStream(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// fps replaces chunk and delay: the body is spread over ?duration= ms
+	// (default 5000) as fps evenly paced flushes per second.
+	var frameInterval time.Duration
+	if f, err := strconv.Atoi(r.URL.Query().Get("fps")); err == nil && f > 0 && f <= 1000 {
+		durationMs := 5000
+		if d, err := strconv.Atoi(r.URL.Query().Get("duration")); err == nil && d > 0 {
+			durationMs = d
+		}
+		frames := max(f*durationMs/1000, 1)
+		chunkSize = max((size+frames-1)/frames, 1)
+		frameInterval = time.Second / time.Duration(f)
+		w.Header().Set("X-Fps", strconv.Itoa(f))
+		w.Header().Set("X-Frame-Interval-Ms", strconv.FormatFloat(frameInterval.Seconds()*1000, 'f', 3, 64))
+	}
+
+	// bps caps the overall throughput with a token bucket; the bucket holds
+	// 100ms worth of bytes and starts empty so there's no initial burst.
+	var limiter *rate.Limiter
+	bps := 0
+	if b, err := strconv.Atoi(r.URL.Query().Get("bps")); err == nil && b > 0 {
+		bps = b
+		burst := max(bps/10, 1)
+		limiter = rate.NewLimiter(rate.Limit(bps), burst)
+		limiter.AllowN(time.Now(), burst)
+	}
+
+	// mode picks the framing: chunked (the default) flushes every chunk and
+	// never declares a length; content-length declares the size up front and
+	// only flushes per chunk when ?flush=true.
+	mode := r.URL.Query().Get("mode")
+	if mode == "" {
+		mode = "chunked"
+	}
+	if mode != "chunked" && mode != "content-length" {
+		http.Error(w, "mode must be content-length or chunked", http.StatusBadRequest)
+		return
+	}
+	flushEach := mode == "chunked"
+	if f, err := strconv.ParseBool(r.URL.Query().Get("flush")); err == nil && mode == "content-length" {
+		flushEach = f
+	}
+
+	// encoding=gzip compresses the stream and flushes the gzip writer with
+	// every chunk, so each chunk still reaches the client as it's written.
+	// The compressed size isn't known up front, and byte ranges would refer
+	// to the compressed representation, so both are unavailable here.
+	encoding := r.URL.Query().Get("encoding")
+	if encoding != "" && encoding != "gzip" {
+		http.Error(w, "encoding must be gzip", http.StatusBadRequest)
+		return
+	}
+	if encoding == "gzip" && mode == "content-length" {
+		http.Error(w, "encoding=gzip can't be combined with mode=content-length", http.StatusBadRequest)
+		return
+	}
+
+	// With ?seed the whole body comes from a seeded PRNG, so it is
+	// reproducible and its checksum can be sent up front.
+	var seeded *rand.Rand
+	if seedStr := r.URL.Query().Get("seed"); seedStr != "" {
+		if s, err := strconv.ParseInt(seedStr, 10, 64); err == nil {
+			h := sha256.New()
+			io.CopyN(h, rand.New(rand.NewSource(s)), int64(size))
+			w.Header().Set("X-Body-Seed", strconv.FormatInt(s, 10))
+			w.Header().Set("X-Body-SHA256", hex.EncodeToString(h.Sum(nil)))
+			seeded = rand.New(rand.NewSource(s))
+		}
+	}
+
+	// A seeded body is fully determined by seed and size, which makes a
+	// strong ETag and lets an interrupted download resume with If-Range.
+	etag := ""
+	if seeded != nil && encoding == "" {
+		etag = fmt.Sprintf(`"s%s-%d"`, w.Header().Get("X-Body-Seed"), size)
+		w.Header().Set("ETag", etag)
+	}
+
+	w.Header().Set("Accept-Ranges", "bytes")
+	start, length, status := 0, size, http.StatusOK
+	if encoding == "" {
+		start, length, status = parseRange(r.Header.Get("Range"), size)
+	}
+	// If-Range only allows the partial response when the client's copy is
+	// still current; otherwise the whole body is sent as a 200. There is no
+	// Last-Modified, so date validators never match.
+	if ifRange := r.Header.Get("If-Range"); ifRange != "" && status != http.StatusOK && (etag == "" || ifRange != etag) {
+		start, length, status = 0, size, http.StatusOK
+	}
+	if status == http.StatusRequestedRangeNotSatisfiable {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+		http.Error(w, "Requested range not satisfiable", status)
+		return
+	}
+
 	w.Header().Set("Content-Type", "application/octet-stream")
 	w.Header().Set("X-Content-Size", strconv.Itoa(size))
 	w.Header().Set("X-Chunk-Size", strconv.Itoa(chunkSize))
+	w.Header().Set("X-Stream-Mode", mode)
+	if mode == "content-length" {
+		w.Header().Set("Content-Length", strconv.Itoa(length))
+	}
 
-	log.Printf("Starting stream: size=%d, chunk=%d, delay=%dms", size, chunkSize, delay)
+	if seeded == nil {
+		w.Header().Set("X-Body-Pattern", "offset-counter")
+	}
 
-	sent := 0
-	chunk := make([]byte, chunkSize)
-	for i := range chunk {
-		chunk[i] = byte(rand.Intn(256))
+	if status == http.StatusPartialContent {
+		w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, start+length-1, size))
+		if seeded != nil {
+			io.CopyN(io.Discard, seeded, int64(start))
+		}
+	}
+	var out io.Writer = w
+	var gz *gzip.Writer
+	if encoding == "gzip" {
+		gz = gzip.NewWriter(w)
+		out = gz
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Header().Set("X-Uncompressed-Length", strconv.Itoa(length))
+		w.Header().Del("Accept-Ranges")
+	}
+
+	// HEAD gets the same headers a GET would, so proxies pre-flighting with
+	// HEAD see the declared size, but no body.
+	if r.Method == http.MethodHead {
+		w.WriteHeader(status)
+		log.Printf("Stream HEAD: size=%d, range=%d+%d, mode=%s", size, start, length, mode)
+		return
+	}
+
+	// Trailers need a chunked body on HTTP/1.1, so there with
+	// mode=content-length the achieved rate is only logged.
+	trailer := limiter != nil && (mode == "chunked" || r.ProtoMajor >= 2)
+	if trailer {
+		w.Header().Set("Trailer", "X-Achieved-Bps")
+	}
+	w.WriteHeader(status)
+
+	log.Printf("Starting stream: size=%d, range=%d+%d, chunk=%d, delay=%dms, interval=%s, mode=%s, flush=%t, seeded=%t, bps=%d, encoding=%s",
+		size, start, length, chunkSize, delay, frameInterval, mode, flushEach, seeded != nil, bps, encoding)
+	streamStart := time.Now()
+
+	// ?id= lets /progress?id= follow this download.
+	var progress *streamProgress
+	if id := r.URL.Query().Get("id"); id != "" {
+		progress = trackProgress(id, length)
+		defer progress.finish(id)
 	}
 
-	for sent < size {
-		remaining := size - sent
+	sent, frame := 0, 0
+	chunk := make([]byte, chunkSize)
+
+	for sent < length {
+		remaining := length - sent
 		toSend := chunkSize
 		if remaining < chunkSize {
 			toSend = remaining
 		}
+		if seeded != nil {
+			seeded.Read(chunk[:toSend])
+		} else {
+			fillOffsets(chunk[:toSend], start+sent)
+		}
 
-		n, err := w.Write(chunk[:toSend])
+		n, err := writeThrottled(r.Context(), out, chunk[:toSend], limiter)
+		sent += n
+		if progress != nil {
+			progress.sent.Store(int64(sent))
+		}
+		if err == nil && gz != nil {
+			err = gz.Flush()
+		}
 		if err != nil {
 			log.Printf("Stream write error after %d bytes: %v", sent, err)
 			return
 		}
-		sent += n
-		flusher.Flush()
+		if flushEach {
+			flusher.Flush()
+		}
 
-		if delay > 0 {
+		frame++
+		if frameInterval > 0 {
+			// Pace against the start time so write time doesn't add drift.
+			time.Sleep(time.Until(streamStart.Add(time.Duration(frame) * frameInterval)))
+		} else if delay > 0 {
 			time.Sleep(time.Duration(delay) * time.Millisecond)
 		}
 	}
 
+	if sent != length {
+		log.Printf("Stream length mismatch: sent %d bytes, declared %d", sent, length)
+		return
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			log.Printf("Stream gzip close error: %v", err)
+			return
+		}
+	}
+	if limiter != nil {
+		achieved := float64(sent) / time.Since(streamStart).Seconds()
+		if trailer {
+			w.Header().Set("X-Achieved-Bps", strconv.FormatFloat(achieved, 'f', 0, 64))
+		}
+		log.Printf("Stream throughput: configured %d B/s, achieved %.0f B/s", bps, achieved)
+	}
 	log.Printf("Stream complete: sent %d bytes", sent)
 }
 
@@ -102,15 +493,28 @@ func handleChunked(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "text/plain")
 	w.Header().Set("Transfer-Encoding", "chunked")
 
+	if r.Method == http.MethodHead {
+		log.Printf("Chunked HEAD: count=%d", count)
+		return
+	}
+
 	log.Printf("Starting chunked response: count=%d, delay=%dms", count, delay)
 
 	for i := 1; i <= count; i++ {
 		msg := fmt.Sprintf("Chunk %d of %d at %s\n", i, count, time.Now().Format(time.RFC3339Nano))
-		w.Write([]byte(msg))
+		if _, err := w.Write([]byte(msg)); err != nil {
+			log.Printf("Chunked write error at chunk %d of %d: %v", i, count, err)
+			return
+		}
 		flusher.Flush()
 
 		if i < count {
-			time.Sleep(time.Duration(delay) * time.Millisecond)
+			select {
+			case <-time.After(time.Duration(delay) * time.Millisecond):
+			case <-r.Context().Done():
+				log.Printf("Chunked response: client gone after chunk %d of %d", i, count)
+				return
+			}
 		}
 	}
 
@@ -134,6 +538,276 @@ func handleSlowHeaders(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(`{"status":"ok","delayed":true}`))
 }
 
+// handleSlowFull delays the headers and then trickles the body, so proxy
+// header (first byte) and body read timeouts can be exercised separately.
+func handleSlowFull(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	headerDelay := 2000
+	if d, err := strconv.Atoi(r.URL.Query().Get("headerDelay")); err == nil && d >= 0 {
+		headerDelay = d
+	}
+
+	bodyDelay := 1000
+	if d, err := strconv.Atoi(r.URL.Query().Get("bodyDelay")); err == nil && d >= 0 {
+		bodyDelay = d
+	}
+
+	chunks := 5
+	if c, err := strconv.Atoi(r.URL.Query().Get("chunks")); err == nil && c > 0 {
+		chunks = c
+	}
+
+	log.Printf("Slow full: headerDelay=%dms, bodyDelay=%dms, chunks=%d", headerDelay, bodyDelay, chunks)
+	start := time.Now()
+
+	select {
+	case <-time.After(time.Duration(headerDelay) * time.Millisecond):
+	case <-r.Context().Done():
+		log.Printf("Slow full: client gone before headers after %s", time.Since(start))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	if r.Method == http.MethodHead {
+		log.Printf("Slow full HEAD: headers sent after %s", time.Since(start))
+		return
+	}
+
+	for i := 1; i <= chunks; i++ {
+		if i > 1 {
+			select {
+			case <-time.After(time.Duration(bodyDelay) * time.Millisecond):
+			case <-r.Context().Done():
+				log.Printf("Slow full: client gone after %d of %d chunks (%s)", i-1, chunks, time.Since(start))
+				return
+			}
+		}
+
+		msg := fmt.Sprintf("Chunk %d of %d at %s\n", i, chunks, time.Now().Format(time.RFC3339Nano))
+		if _, err := w.Write([]byte(msg)); err != nil {
+			log.Printf("Slow full write error at chunk %d: %v", i, err)
+			return
+		}
+		flusher.Flush()
+	}
+
+	log.Printf("Slow full complete: %d chunks in %s", chunks, time.Since(start))
+}
+
+// handleBadLength declares one Content-Length and sends a different number
+// of body bytes. Over HTTP/1.x the connection is hijacked so the mismatch
+// really goes out on the wire (net/http would otherwise refuse to write past
+// the declared length), and then closed. Over HTTP/2, DATA frames and
+// END_STREAM do the framing and content-length is advisory; RFC 9113 still
+// requires the peer to treat a mismatch as malformed (curl resets the stream
+// with PROTOCOL_ERROR), so this shows whether a proxy checks or forwards it.
+func handleBadLength(w http.ResponseWriter, r *http.Request) {
+	declared := 100
+	if d, err := strconv.Atoi(r.URL.Query().Get("declared")); err == nil && d >= 0 {
+		declared = d
+	}
+
+	actual := 50
+	if a, err := strconv.Atoi(r.URL.Query().Get("actual")); err == nil && a >= 0 {
+		actual = a
+	}
+
+	if r.Method == http.MethodHead {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Length", strconv.Itoa(declared))
+		log.Printf("Bad length HEAD: declared Content-Length %d", declared)
+		return
+	}
+
+	log.Printf("Bad length: declared Content-Length %d, sending %d bytes (%s)", declared, actual, r.Proto)
+
+	if r.ProtoMajor == 1 {
+		hj, ok := w.(http.Hijacker)
+		if !ok {
+			http.Error(w, "Hijacking not supported", http.StatusInternalServerError)
+			return
+		}
+		conn, bufrw, err := hj.Hijack()
+		if err != nil {
+			log.Printf("Bad length hijack error: %v", err)
+			return
+		}
+		defer conn.Close()
+
+		fmt.Fprintf(bufrw, "HTTP/1.1 200 OK\r\nContent-Type: application/octet-stream\r\nContent-Length: %d\r\n\r\n", declared)
+		writeOffsets(bufrw, actual)
+		if err := bufrw.Flush(); err != nil {
+			log.Printf("Bad length write error: %v", err)
+			return
+		}
+		log.Printf("Bad length complete: wrote %d of %d declared bytes, closing connection", actual, declared)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Length", strconv.Itoa(declared))
+	n, err := writeOffsets(w, actual)
+	if err != nil {
+		log.Printf("Bad length write error after %d bytes: %v", n, err)
+		return
+	}
+	log.Printf("Bad length complete: wrote %d of %d declared bytes", n, declared)
+}
+
+const mjpegBoundary = "frame"
+
+// mjpegFrame renders a small solid-colour JPEG whose hue shifts per frame, so
+// a viewer can see frames being replaced.
+func mjpegFrame(i int) ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 48))
+	c := color.RGBA{R: uint8(i * 40), G: uint8(255 - i*40), B: uint8(i * 90), A: 255}
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: c}, image.Point{}, draw.Src)
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// handleMJPEG streams frames as multipart/x-mixed-replace, the framing used
+// by MJPEG cameras, flushing after each part.
+func handleMJPEG(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	frames := 10
+	if f, err := strconv.Atoi(r.URL.Query().Get("frames")); err == nil && f > 0 {
+		frames = f
+	}
+
+	delay := 200
+	if d, err := strconv.Atoi(r.URL.Query().Get("delay")); err == nil && d >= 0 {
+		delay = d
+	}
+
+	w.Header().Set("Content-Type", "multipart/x-mixed-replace; boundary="+mjpegBoundary)
+	w.Header().Set("Cache-Control", "no-store")
+
+	if r.Method == http.MethodHead {
+		log.Printf("MJPEG HEAD: frames=%d", frames)
+		return
+	}
+
+	log.Printf("MJPEG: frames=%d, delay=%dms", frames, delay)
+
+	for i := 1; i <= frames; i++ {
+		frame, err := mjpegFrame(i)
+		if err != nil {
+			log.Printf("MJPEG encode error at frame %d: %v", i, err)
+			return
+		}
+
+		part := fmt.Sprintf("--%s\r\nContent-Type: image/jpeg\r\nContent-Length: %d\r\nX-Frame: %d\r\n\r\n", mjpegBoundary, len(frame), i)
+		if _, err := w.Write(append(append([]byte(part), frame...), "\r\n"...)); err != nil {
+			log.Printf("MJPEG write error at frame %d: %v", i, err)
+			return
+		}
+		flusher.Flush()
+
+		if i < frames {
+			select {
+			case <-time.After(time.Duration(delay) * time.Millisecond):
+			case <-r.Context().Done():
+				log.Printf("MJPEG: client gone after %d of %d frames", i, frames)
+				return
+			}
+		}
+	}
+
+	w.Write([]byte("--" + mjpegBoundary + "--\r\n"))
+	log.Printf("MJPEG complete: sent %d frames", frames)
+}
+
+// handleStall sends headers and a single byte, then writes nothing more
+// until the client (or proxy) gives up, to measure idle/read timeouts.
+func handleStall(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+	if r.Method == http.MethodHead {
+		log.Printf("Stall HEAD: headers only")
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write([]byte("s")); err != nil {
+		log.Printf("Stall write error: %v", err)
+		return
+	}
+	flusher.Flush()
+
+	log.Printf("Stall: sent 1 byte, waiting for the client to disconnect (%s)", r.Proto)
+	start := time.Now()
+	<-r.Context().Done()
+	log.Printf("Stall: connection closed after %s blocked", time.Since(start))
+}
+
+// tuningListener applies socket options to every accepted connection, to
+// see whether Nagle's algorithm or send buffering changes chunk timing as
+// observed through a proxy.
+type tuningListener struct {
+	net.Listener
+	noDelay     bool
+	writeBuffer int
+}
+
+func (l tuningListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	if tc, ok := conn.(*net.TCPConn); ok {
+		if err := tc.SetNoDelay(l.noDelay); err != nil {
+			log.Printf("SetNoDelay(%t) on %s: %v", l.noDelay, conn.RemoteAddr(), err)
+		}
+		if l.writeBuffer > 0 {
+			if err := tc.SetWriteBuffer(l.writeBuffer); err != nil {
+				log.Printf("SetWriteBuffer(%d) on %s: %v", l.writeBuffer, conn.RemoteAddr(), err)
+			}
+		}
+	}
+	return conn, nil
+}
+
+// serverConfig is served on /config. The TCP fields matter most here,
+// since they change how chunks are coalesced on the wire.
+type serverConfig struct {
+	Server         string `json:"server"`
+	Addr           string `json:"addr"`
+	TLS            bool   `json:"tls"`
+	TCPNoDelay     bool   `json:"tcp_nodelay"`
+	TCPWriteBuffer int    `json:"tcp_write_buffer"`
+}
+
+func handleConfig(cfg serverConfig, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cfg)
+}
+
 func handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -362,23 +1036,53 @@ func main() {
 	addr := flag.String("addr", ":8080", "HTTP service address")
 	tlsCert := flag.String("cert", "", "TLS certificate file (enables HTTPS)")
 	tlsKey := flag.String("key", "", "TLS key file")
+	noDelay := flag.Bool("tcp-nodelay", true, "Set TCP_NODELAY on accepted connections (false enables Nagle's algorithm)")
+	writeBuffer := flag.Int("tcp-write-buffer", 0, "Socket send buffer size in bytes for accepted connections (0 = OS default)")
 	flag.Parse()
 
+	tlsEnabled := *tlsCert != "" && *tlsKey != ""
+	cfg := serverConfig{
+		Server:         "streaming",
+		Addr:           *addr,
+		TLS:            tlsEnabled,
+		TCPNoDelay:     *noDelay,
+		TCPWriteBuffer: *writeBuffer,
+	}
+
 	http.HandleFunc("/stream", handleStream)
 	http.HandleFunc("/chunked", handleChunked)
 	http.HandleFunc("/slow", handleSlowHeaders)
+	http.HandleFunc("/progress", handleProgress)
+	http.HandleFunc("/slowfull", handleSlowFull)
+	http.HandleFunc("/stall", handleStall)
+	http.HandleFunc("/badlength", handleBadLength)
+	http.HandleFunc("/mjpeg", handleMJPEG)
+	http.HandleFunc("/config", func(w http.ResponseWriter, r *http.Request) {
+		handleConfig(cfg, w, r)
+	})
 	http.HandleFunc("/health", handleHealth)
+	// Nothing needs initializing beyond the listener, so ready means live.
+	http.HandleFunc("/livez", handleHealth)
+	http.HandleFunc("/readyz", handleHealth)
 
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/html")
 		w.Write([]byte(clientHTML))
 	})
 
-	if *tlsCert != "" && *tlsKey != "" {
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		log.Fatal(err)
+	}
+	ln = tuningListener{Listener: ln, noDelay: *noDelay, writeBuffer: *writeBuffer}
+	log.Printf("Socket options: tcp-nodelay=%t, tcp-write-buffer=%d (0 = OS default)", *noDelay, *writeBuffer)
+
+	server := &http.Server{Addr: *addr}
+	if tlsEnabled {
 		log.Printf("Starting HTTPS streaming server on %s", *addr)
-		log.Fatal(http.ListenAndServeTLS(*addr, *tlsCert, *tlsKey, nil))
+		log.Fatal(server.ServeTLS(ln, *tlsCert, *tlsKey))
 	} else {
 		log.Printf("Starting HTTP streaming server on %s", *addr)
-		log.Fatal(http.ListenAndServe(*addr, nil))
+		log.Fatal(server.Serve(ln))
 	}
 }
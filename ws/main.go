@@ -1,109 +1,729 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
 
-var upgrader = websocket.Upgrader{
-	CheckOrigin: func(r *http.Request) bool {
-		return true
-	},
+var upgrader = websocket.Upgrader{}
+
+// originChecker returns a CheckOrigin that accepts any origin for "*" and
+// otherwise only the comma-separated origins listed, compared without
+// regard to case. Requests without an Origin header are not from a browser
+// and are always accepted. gorilla answers a rejected handshake with 403.
+func originChecker(allowed string) func(r *http.Request) bool {
+	origins := make(map[string]bool)
+	for _, o := range strings.Split(allowed, ",") {
+		if o = strings.TrimSpace(o); o != "" {
+			origins[strings.ToLower(o)] = true
+		}
+	}
+	return func(r *http.Request) bool {
+		origin := r.Header.Get("Origin")
+		if origins["*"] || origin == "" || origins[strings.ToLower(origin)] {
+			return true
+		}
+		log.Printf("Rejected %s: origin %q not allowed", r.RemoteAddr, origin)
+		return false
+	}
+}
+
+// wsConfig holds the per-connection keepalive settings from flags.
+type wsConfig struct {
+	// pingInterval is how often the server pings each client; 0 disables
+	// pings and the read deadline.
+	pingInterval time.Duration
+	// pongWait is how long a connection may go without any message or pong
+	// before it is considered dead. It must exceed pingInterval.
+	pongWait time.Duration
+	// readLimit is the largest message accepted from a client, in bytes;
+	// larger ones close the connection with 1009. 0 means no limit.
+	readLimit int64
+	// pushInterval is how often each client is sent a server-initiated
+	// message; 0 disables pushes.
+	pushInterval time.Duration
+}
+
+const (
+	// writeWait bounds each frame write.
+	writeWait = 10 * time.Second
+	// sendBufferSize is how many outgoing messages a client may have queued.
+	sendBufferSize = 256
+	// defaultRoom is used when /ws is opened without ?room=.
+	defaultRoom = "lobby"
+	// maxEchoDelay caps ?echo-delay and delay: commands.
+	maxEchoDelay = time.Minute
+	// maxFloodBytes caps the total size of one flood: command.
+	maxFloodBytes = 1 << 30
+	// maxFloodMessage caps the size of each flood message, which is what
+	// the server actually allocates.
+	maxFloodMessage = 16 << 20
+	// floodHeadroom is how many send slots a flood leaves free, so room
+	// broadcasts still fit and don't disconnect the client as too slow.
+	floodHeadroom = sendBufferSize / 4
+)
+
+// wsStats counts traffic for /stats.
+type wsStats struct {
+	received        atomic.Int64
+	sent            atomic.Int64
+	broadcasts      atomic.Int64
+	upgradeFailures atomic.Int64
+}
+
+var stats wsStats
+
+// Client is one WebSocket connection. Only writePump writes to conn; everyone
+// else queues messages on send, since gorilla allows a single writer.
+type Client struct {
+	hub  *Hub
+	conn *websocket.Conn
+	// send is never closed: timers, pumps and floods may still be queueing
+	// when the hub drops the client, so the hub closes quit instead.
+	send chan []byte
+	// quit is closed (once, via stop) when the hub drops the client, which
+	// makes writePump send a close frame and exit.
+	quit     chan struct{}
+	quitOnce sync.Once
+	// done is closed when writePump exits, so senders never block on a
+	// client that is no longer draining send.
+	done chan struct{}
+	// room is the client's current room. It is set before registration
+	// and afterwards only changed by the hub.
+	room string
+	// echoDelay holds back every echo on this connection (?echo-delay=ms).
+	echoDelay time.Duration
+	// flooding is set while a flood: command runs; a client gets one at a
+	// time.
+	flooding atomic.Bool
+}
+
+// roomMessage is a broadcast scoped to one room.
+type roomMessage struct {
+	room string
+	data []byte
+}
+
+// joinRequest moves a client into another room.
+type joinRequest struct {
+	client *Client
+	room   string
+}
+
+// shutdownRequest asks the hub to close every client with 1001, giving
+// each close frame up to timeout to be written.
+type shutdownRequest struct {
+	timeout time.Duration
+	done    chan struct{}
 }
 
 type Hub struct {
-	clients    map[*websocket.Conn]bool
-	broadcast  chan []byte
-	register   chan *websocket.Conn
-	unregister chan *websocket.Conn
+	clients    map[*Client]bool
+	rooms      map[string]map[*Client]bool
+	broadcast  chan roomMessage
+	join       chan joinRequest
+	register   chan *Client
+	unregister chan *Client
+	shutdown   chan shutdownRequest
 	mu         sync.RWMutex
+
+	// closed is only touched by run; once set, new clients are turned
+	// away with 1001.
+	closed bool
+	// ready is set while run is accepting clients, for /readyz.
+	ready atomic.Bool
 }
 
 func newHub() *Hub {
 	return &Hub{
-		clients:    make(map[*websocket.Conn]bool),
-		broadcast:  make(chan []byte),
-		register:   make(chan *websocket.Conn),
-		unregister: make(chan *websocket.Conn),
+		clients:    make(map[*Client]bool),
+		rooms:      make(map[string]map[*Client]bool),
+		broadcast:  make(chan roomMessage),
+		join:       make(chan joinRequest),
+		register:   make(chan *Client),
+		unregister: make(chan *Client),
+		shutdown:   make(chan shutdownRequest),
+	}
+}
+
+// goingAway sends client a 1001 close frame. WriteControl is safe to call
+// alongside writePump.
+func goingAway(client *Client, timeout time.Duration) {
+	msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+	if err := client.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(timeout)); err != nil {
+		log.Printf("Close frame to %s failed: %v", client.conn.RemoteAddr(), err)
+	}
+}
+
+// Shutdown sends every connected client a 1001 close frame and removes it
+// from the hub, returning once all frames are written or timed out.
+func (h *Hub) Shutdown(timeout time.Duration) {
+	done := make(chan struct{})
+	h.shutdown <- shutdownRequest{timeout: timeout, done: done}
+	<-done
+}
+
+// addToRoom and removeFromRoom update room membership; the caller keeps
+// client.room in step. Both must be called with h.mu held, and empty rooms
+// are deleted.
+func (h *Hub) addToRoom(client *Client, room string) {
+	members := h.rooms[room]
+	if members == nil {
+		members = make(map[*Client]bool)
+		h.rooms[room] = members
 	}
+	members[client] = true
+}
+
+func (h *Hub) removeFromRoom(client *Client) {
+	members := h.rooms[client.room]
+	delete(members, client)
+	if len(members) == 0 {
+		delete(h.rooms, client.room)
+	}
+}
+
+// remove drops client from the hub and stops it, which makes writePump
+// send a close frame. It must be called with h.mu held.
+func (h *Hub) remove(client *Client) {
+	h.removeFromRoom(client)
+	delete(h.clients, client)
+	client.stop()
 }
 
 func (h *Hub) run() {
+	h.ready.Store(true)
 	for {
 		select {
-		case conn := <-h.register:
+		case client := <-h.register:
+			if h.closed {
+				goingAway(client, writeWait)
+				client.stop()
+				continue
+			}
 			h.mu.Lock()
-			h.clients[conn] = true
+			h.clients[client] = true
+			h.addToRoom(client, client.room)
 			count := len(h.clients)
 			h.mu.Unlock()
-			log.Printf("Client connected. Total: %d", count)
+			log.Printf("Client connected to room %q. Total: %d", client.room, count)
 
-		case conn := <-h.unregister:
+		case client := <-h.unregister:
 			h.mu.Lock()
-			if _, ok := h.clients[conn]; ok {
-				delete(h.clients, conn)
-				conn.Close()
+			if _, ok := h.clients[client]; ok {
+				h.remove(client)
 			}
 			count := len(h.clients)
 			h.mu.Unlock()
 			log.Printf("Client disconnected. Total: %d", count)
 
-		case message := <-h.broadcast:
+		case req := <-h.join:
+			h.mu.Lock()
+			if _, ok := h.clients[req.client]; ok {
+				from := req.client.room
+				h.removeFromRoom(req.client)
+				h.addToRoom(req.client, req.room)
+				req.client.room = req.room
+				log.Printf("Client %s moved from room %q to %q", req.client.conn.RemoteAddr(), from, req.room)
+			}
+			h.mu.Unlock()
+
+		case req := <-h.shutdown:
+			h.closed = true
+			h.ready.Store(false)
+			// The close frames can take up to req.timeout, so they go out
+			// on a copy of the client set without holding h.mu; /stats and
+			// /readyz keep answering meanwhile. run is the only writer, so
+			// the set can't change in between.
 			h.mu.RLock()
-			for conn := range h.clients {
-				err := conn.WriteMessage(websocket.TextMessage, message)
-				if err != nil {
-					log.Printf("Broadcast error: %v", err)
-				}
+			clients := make([]*Client, 0, len(h.clients))
+			for client := range h.clients {
+				clients = append(clients, client)
 			}
 			h.mu.RUnlock()
+			count := len(clients)
+			var wg sync.WaitGroup
+			for _, client := range clients {
+				wg.Add(1)
+				go func(client *Client) {
+					defer wg.Done()
+					goingAway(client, req.timeout)
+				}(client)
+			}
+			wg.Wait()
+			h.mu.Lock()
+			for _, client := range clients {
+				h.remove(client)
+			}
+			h.mu.Unlock()
+			log.Printf("Sent 1001 close to %d clients", count)
+			close(req.done)
+
+		case message := <-h.broadcast:
+			stats.broadcasts.Add(1)
+			h.mu.Lock()
+			for client := range h.rooms[message.room] {
+				select {
+				case client.send <- message.data:
+				default:
+					// A client this far behind is dropped rather than
+					// stalling the broadcast for everyone else.
+					log.Printf("Client %s send buffer full, disconnecting", client.conn.RemoteAddr())
+					h.remove(client)
+				}
+			}
+			h.mu.Unlock()
 		}
 	}
 }
 
-func handleWebSocket(hub *Hub, w http.ResponseWriter, r *http.Request) {
-	conn, err := upgrader.Upgrade(w, r, nil)
+// stop tells writePump to close the connection. It is safe to call more
+// than once.
+func (c *Client) stop() {
+	c.quitOnce.Do(func() { close(c.quit) })
+}
+
+// queue hands message to writePump, reporting false if the client is gone.
+func (c *Client) queue(message []byte) bool {
+	select {
+	case <-c.quit:
+		return false
+	default:
+	}
+	select {
+	case c.send <- message:
+		return true
+	case <-c.quit:
+		return false
+	case <-c.done:
+		return false
+	}
+}
+
+// parseCloseCommand parses "close:<code>:<reason>" (the reason is
+// optional) and checks that the code may be sent in a close frame: 1000-1003,
+// 1007-1014, or the registered and private ranges 3000-4999. 1004-1006 and
+// 1015 are reserved and never appear on the wire.
+func parseCloseCommand(s string) (code int, reason string, err error) {
+	codeStr, reason, _ := strings.Cut(strings.TrimPrefix(s, "close:"), ":")
+	code, err = strconv.Atoi(codeStr)
 	if err != nil {
-		log.Printf("Upgrade error: %v", err)
-		return
+		return 0, "", fmt.Errorf("invalid close code %q", codeStr)
 	}
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1014, code >= 3000 && code <= 4999:
+	default:
+		return 0, "", fmt.Errorf("close code %d cannot be sent", code)
+	}
+	// Control frame payloads are limited to 125 bytes, two of them the code.
+	if len(reason) > 123 {
+		return 0, "", fmt.Errorf("close reason is %d bytes, limit is 123", len(reason))
+	}
+	return code, reason, nil
+}
 
-	hub.register <- conn
+// readPump reads messages until the connection fails, then unregisters
+// the client. Every pong or message pushes the read deadline out, so a
+// client that stops answering pings times out here.
+func (c *Client) readPump(cfg wsConfig) {
+	// Only the hub may touch c.room once registered, so track the room
+	// this goroutine last asked for locally.
+	room := c.room
 
 	defer func() {
-		hub.unregister <- conn
+		c.hub.unregister <- c
+		c.conn.Close()
 	}()
 
+	if cfg.readLimit > 0 {
+		c.conn.SetReadLimit(cfg.readLimit)
+	}
+	if cfg.pingInterval > 0 {
+		c.conn.SetReadDeadline(time.Now().Add(cfg.pongWait))
+		c.conn.SetPongHandler(func(appData string) error {
+			// writePump stamps each ping with its send time.
+			if sent, err := strconv.ParseInt(appData, 10, 64); err == nil {
+				rtt := time.Since(time.Unix(0, sent))
+				log.Printf("Pong from %s, RTT %.3fms", c.conn.RemoteAddr(), float64(rtt.Microseconds())/1000)
+			}
+			return c.conn.SetReadDeadline(time.Now().Add(cfg.pongWait))
+		})
+	}
+
 	for {
-		messageType, message, err := conn.ReadMessage()
+		messageType, message, err := c.conn.ReadMessage()
 		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+			if errors.Is(err, websocket.ErrReadLimit) {
+				// gorilla has already sent the 1009 close frame.
+				log.Printf("Client %s exceeded read limit of %d bytes", c.conn.RemoteAddr(), cfg.readLimit)
+			} else if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("Read error: %v", err)
+			} else if ne, ok := err.(net.Error); ok && ne.Timeout() {
+				log.Printf("Client %s missed pong deadline", c.conn.RemoteAddr())
 			}
-			break
+			return
+		}
+		stats.received.Add(1)
+		if cfg.pingInterval > 0 {
+			c.conn.SetReadDeadline(time.Now().Add(cfg.pongWait))
 		}
 
 		log.Printf("Received: %s", message)
 
 		if messageType == websocket.TextMessage {
-			if string(message) == "broadcast" {
-				hub.broadcast <- []byte(fmt.Sprintf("Broadcast from server at %s", r.RemoteAddr))
-			} else {
-				err = conn.WriteMessage(websocket.TextMessage, []byte(fmt.Sprintf("Echo: %s", message)))
+			var control struct {
+				Join *string `json:"join"`
+			}
+			if json.Unmarshal(message, &control) == nil && control.Join != nil {
+				if *control.Join == "" {
+					if !c.queue([]byte("Error: room name required")) {
+						return
+					}
+					continue
+				}
+				// join is unbuffered, so the move is done before later
+				// broadcasts from this client reach the hub.
+				room = *control.Join
+				c.hub.join <- joinRequest{client: c, room: room}
+				if !c.queue([]byte(fmt.Sprintf("Joined: %s", room))) {
+					return
+				}
+			} else if strings.HasPrefix(string(message), "close:") {
+				code, reason, err := parseCloseCommand(string(message))
 				if err != nil {
-					log.Printf("Write error: %v", err)
-					break
+					if !c.queue([]byte("Error: " + err.Error())) {
+						return
+					}
+					continue
+				}
+				log.Printf("Closing %s with %d %q on request", c.conn.RemoteAddr(), code, reason)
+				if err := c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(writeWait)); err != nil {
+					log.Printf("Close write error: %v", err)
+					return
+				}
+				// Keep reading so the client's answering close frame is
+				// consumed, but don't wait on it forever.
+				c.conn.SetReadDeadline(time.Now().Add(writeWait))
+			} else if string(message) == "broadcast" {
+				c.hub.broadcast <- roomMessage{room: room, data: []byte(fmt.Sprintf("Broadcast from server at %s to room %s", c.conn.RemoteAddr(), room))}
+			} else if ts, ok := strings.CutPrefix(string(message), "ping:"); ok {
+				// App-level RTT probe: answered straight away, skipping
+				// any echo delay, so the client can time the round trip.
+				if !c.queue([]byte("pong:" + ts)) {
+					return
+				}
+			} else if strings.HasPrefix(string(message), "flood:") {
+				countStr, sizeStr, _ := strings.Cut(strings.TrimPrefix(string(message), "flood:"), ":")
+				count, err1 := strconv.Atoi(countStr)
+				size, err2 := strconv.Atoi(sizeStr)
+				if err1 != nil || err2 != nil || count < 1 || size < 1 || size > maxFloodMessage || int64(count)*int64(size) > maxFloodBytes {
+					if !c.queue([]byte(fmt.Sprintf("Error: usage flood:<count>:<size>, at most %d bytes per message and %d bytes in total", maxFloodMessage, maxFloodBytes))) {
+						return
+					}
+					continue
+				}
+				if !c.flooding.CompareAndSwap(false, true) {
+					if !c.queue([]byte("Error: a flood is already running")) {
+						return
+					}
+					continue
 				}
+				go c.flood(count, size)
+			} else if strings.HasPrefix(string(message), "delay:") {
+				// delay:<ms>:<text> echoes text after ms, overriding
+				// ?echo-delay for this message.
+				msStr, text, _ := strings.Cut(strings.TrimPrefix(string(message), "delay:"), ":")
+				ms, err := strconv.Atoi(msStr)
+				if err != nil || ms < 0 || time.Duration(ms)*time.Millisecond > maxEchoDelay {
+					if !c.queue([]byte(fmt.Sprintf("Error: delay must be 0-%d ms", maxEchoDelay.Milliseconds()))) {
+						return
+					}
+					continue
+				}
+				c.echo(text, time.Duration(ms)*time.Millisecond)
+			} else {
+				c.echo(string(message), c.echoDelay)
 			}
 		}
 	}
 }
 
+// writePump is the connection's only writer. It sends queued messages and
+// pings every cfg.pingInterval, and sends a close frame once the hub
+// stops the client.
+func (c *Client) writePump(cfg wsConfig) {
+	var ping <-chan time.Time
+	if cfg.pingInterval > 0 {
+		ticker := time.NewTicker(cfg.pingInterval)
+		defer ticker.Stop()
+		ping = ticker.C
+	}
+	defer func() {
+		close(c.done)
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case <-c.quit:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+			return
+		case message := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				log.Printf("Write error: %v", err)
+				return
+			}
+			stats.sent.Add(1)
+		case <-ping:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			stamp := strconv.FormatInt(time.Now().UnixNano(), 10)
+			if err := c.conn.WriteMessage(websocket.PingMessage, []byte(stamp)); err != nil {
+				log.Printf("Ping to %s failed: %v", c.conn.RemoteAddr(), err)
+				return
+			}
+		}
+	}
+}
+
+// offersDeflate reports whether the client's handshake offers
+// permessage-deflate, which gorilla accepts whenever compression is enabled.
+func offersDeflate(r *http.Request) bool {
+	for _, v := range r.Header.Values("Sec-WebSocket-Extensions") {
+		for _, ext := range strings.Split(v, ",") {
+			name, _, _ := strings.Cut(ext, ";")
+			if strings.TrimSpace(name) == "permessage-deflate" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// echo queues "Echo: <text>" after delay. Delayed echoes are queued from a
+// timer, so neither this connection's reads and pings nor the hub wait on
+// them; if the client is gone by then, queue drops the echo.
+func (c *Client) echo(text string, delay time.Duration) {
+	msg := []byte(fmt.Sprintf("Echo: %s", text))
+	if delay <= 0 {
+		c.queue(msg)
+		return
+	}
+	time.AfterFunc(delay, func() {
+		c.queue(msg)
+	})
+}
+
+// floodReport is sent when a flood: command finishes.
+type floodReport struct {
+	Type      string  `json:"type"`
+	Messages  int     `json:"messages"`
+	Bytes     int64   `json:"bytes"`
+	ElapsedMs float64 `json:"elapsed_ms"`
+	Mbps      float64 `json:"mbps"`
+}
+
+// flood queues count messages of size bytes as fast as the send channel
+// accepts them, then reports throughput. It keeps floodHeadroom slots free
+// for broadcasts and stops early if the client disconnects. The timer stops
+// once the queue has drained into writePump, so the last message is at most
+// being written.
+func (c *Client) flood(count, size int) {
+	defer c.flooding.Store(false)
+	payload := bytes.Repeat([]byte("x"), size)
+	start := time.Now()
+	for i := 0; i < count; i++ {
+		if !c.waitForRoom(floodHeadroom) || !c.queue(payload) {
+			log.Printf("Flood to %s stopped after %d of %d messages", c.conn.RemoteAddr(), i, count)
+			return
+		}
+	}
+	for len(c.send) > 0 {
+		select {
+		case <-c.done:
+			return
+		case <-time.After(time.Millisecond):
+		}
+	}
+	elapsed := time.Since(start)
+
+	total := int64(count) * int64(size)
+	report := floodReport{
+		Type:      "flood_done",
+		Messages:  count,
+		Bytes:     total,
+		ElapsedMs: float64(elapsed.Microseconds()) / 1000,
+		Mbps:      float64(total*8) / elapsed.Seconds() / 1e6,
+	}
+	log.Printf("Flood to %s: %d x %d bytes in %s", c.conn.RemoteAddr(), count, size, elapsed)
+	msg, _ := json.Marshal(report)
+	c.queue(msg)
+}
+
+// waitForRoom blocks until at least n+1 send slots are free, reporting
+// false if the client goes away first.
+func (c *Client) waitForRoom(n int) bool {
+	for len(c.send) >= cap(c.send)-n {
+		select {
+		case <-c.quit:
+			return false
+		case <-c.done:
+			return false
+		case <-time.After(time.Millisecond):
+		}
+	}
+	return true
+}
+
+// pushMessage is the JSON sent to clients every -push-interval.
+type pushMessage struct {
+	Type string    `json:"type"`
+	Seq  uint64    `json:"seq"`
+	Time time.Time `json:"time"`
+}
+
+// pushPump queues a pushMessage every interval until the client's
+// writePump exits.
+func (c *Client) pushPump(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var seq uint64
+	for {
+		select {
+		case <-c.done:
+			return
+		case t := <-ticker.C:
+			seq++
+			msg, _ := json.Marshal(pushMessage{Type: "push", Seq: seq, Time: t})
+			if !c.queue(msg) {
+				return
+			}
+		}
+	}
+}
+
+func handleWebSocket(hub *Hub, cfg wsConfig, w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		stats.upgradeFailures.Add(1)
+		log.Printf("Upgrade error: %v", err)
+		return
+	}
+	log.Printf("Upgraded %s (compression offered=%t, negotiated=%t; subprotocols offered=%q, negotiated=%q)",
+		conn.RemoteAddr(), offersDeflate(r), upgrader.EnableCompression && offersDeflate(r),
+		websocket.Subprotocols(r), conn.Subprotocol())
+
+	room := r.URL.Query().Get("room")
+	if room == "" {
+		room = defaultRoom
+	}
+	client := &Client{
+		hub:  hub,
+		conn: conn,
+		send: make(chan []byte, sendBufferSize),
+		quit: make(chan struct{}),
+		done: make(chan struct{}),
+		room: room,
+	}
+	if ms, err := strconv.Atoi(r.URL.Query().Get("echo-delay")); err == nil && ms > 0 {
+		client.echoDelay = min(time.Duration(ms)*time.Millisecond, maxEchoDelay)
+	}
+	// gorilla already returns the choice in Sec-WebSocket-Protocol; repeating
+	// it in-band shows whether a proxy changed the header on the way back.
+	// It is queued before registering, while send is still empty, so no
+	// broadcast can get ahead of it.
+	if len(upgrader.Subprotocols) > 0 {
+		client.send <- []byte(fmt.Sprintf("Subprotocol: %q", conn.Subprotocol()))
+	}
+	hub.register <- client
+
+	go client.writePump(cfg)
+	if cfg.pushInterval > 0 {
+		go client.pushPump(cfg.pushInterval)
+	}
+	client.readPump(cfg)
+}
+
+// counts returns the number of connected clients and non-empty rooms.
+func (h *Hub) counts() (clients, rooms int) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients), len(h.rooms)
+}
+
+func handleStats(hub *Hub, w http.ResponseWriter, r *http.Request) {
+	clients, rooms := hub.counts()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"clients":           clients,
+		"rooms":             rooms,
+		"messages_received": stats.received.Load(),
+		"messages_sent":     stats.sent.Load(),
+		"broadcasts":        stats.broadcasts.Load(),
+		"upgrade_failures":  stats.upgradeFailures.Load(),
+	})
+}
+
+// serverConfig is served on /config: keepalive timing, buffer sizes and
+// the subprotocols and origins the upgrader will accept.
+type serverConfig struct {
+	Server            string   `json:"server"`
+	Addr              string   `json:"addr"`
+	TLS               bool     `json:"tls"`
+	PingIntervalMs    int64    `json:"ping_interval_ms"`
+	PongWaitMs        int64    `json:"pong_wait_ms"`
+	PushIntervalMs    int64    `json:"push_interval_ms"`
+	Compression       bool     `json:"compression"`
+	ReadBuffer        int      `json:"read_buffer"`
+	WriteBuffer       int      `json:"write_buffer"`
+	ReadLimit         int64    `json:"read_limit"`
+	AllowedOrigins    string   `json:"allowed_origins"`
+	Subprotocols      []string `json:"subprotocols"`
+	ShutdownTimeoutMs int64    `json:"shutdown_timeout_ms"`
+}
+
+func handleConfig(cfg serverConfig, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(cfg)
+}
+
+// handleReadyz reports whether the hub is accepting clients: not before it
+// starts, and not once shutdown has begun. /livez only says the process is
+// up.
+func handleReadyz(hub *Hub, w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if !hub.ready.Load() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"status":"unavailable","reason":"hub not running"}`))
+		return
+	}
+	w.Write([]byte(`{"status":"ok"}`))
+}
+
 func handleHealth(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
@@ -241,27 +861,108 @@ func main() {
 	addr := flag.String("addr", ":8080", "HTTP service address")
 	tlsCert := flag.String("cert", "", "TLS certificate file (enables HTTPS/WSS)")
 	tlsKey := flag.String("key", "", "TLS key file")
+	pingInterval := flag.Duration("ping-interval", 54*time.Second, "Interval between server pings, 0 to disable")
+	compression := flag.Bool("compression", false, "Negotiate permessage-deflate when the client offers it")
+	readBuffer := flag.Int("read-buffer", 0, "Upgrader read buffer size in bytes (0 = gorilla default)")
+	writeBuffer := flag.Int("write-buffer", 0, "Upgrader write buffer size in bytes (0 = gorilla default)")
+	readLimit := flag.Int64("read-limit", 0, "Maximum incoming message size in bytes, 0 for no limit (counts compressed bytes when deflate is negotiated)")
+	allowedOrigins := flag.String("allowed-origins", "*", "Comma-separated origins allowed to connect, or * for any")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 2*time.Second, "How long to wait for close frames and in-flight requests on shutdown")
+	pushInterval := flag.Duration("push-interval", 0, "Interval between server-pushed messages to each client (e.g., 5s), 0 to disable")
+	subprotocols := flag.String("subprotocols", "", "Comma-separated subprotocols the server accepts, in order of preference")
+	pongWait := flag.Duration("pong-wait", 60*time.Second, "How long to wait for a pong (or any message) before dropping the client")
 	flag.Parse()
 
+	if *pingInterval > 0 && *pongWait <= *pingInterval {
+		log.Fatalf("-pong-wait (%s) must be longer than -ping-interval (%s)", *pongWait, *pingInterval)
+	}
+	cfg := wsConfig{pingInterval: *pingInterval, pongWait: *pongWait, readLimit: *readLimit, pushInterval: *pushInterval}
+
+	for _, p := range strings.Split(*subprotocols, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			upgrader.Subprotocols = append(upgrader.Subprotocols, p)
+		}
+	}
+	upgrader.CheckOrigin = originChecker(*allowedOrigins)
+	upgrader.EnableCompression = *compression
+	upgrader.ReadBufferSize = *readBuffer
+	upgrader.WriteBufferSize = *writeBuffer
+	log.Printf("WebSocket settings: compression=%t, read-buffer=%d, write-buffer=%d, read-limit=%d (0 = default), subprotocols=%q",
+		*compression, *readBuffer, *writeBuffer, *readLimit, upgrader.Subprotocols)
+
+	tlsEnabled := *tlsCert != "" && *tlsKey != ""
+	serverCfg := serverConfig{
+		Server:            "ws",
+		Addr:              *addr,
+		TLS:               tlsEnabled,
+		PingIntervalMs:    pingInterval.Milliseconds(),
+		PongWaitMs:        pongWait.Milliseconds(),
+		PushIntervalMs:    pushInterval.Milliseconds(),
+		Compression:       *compression,
+		ReadBuffer:        *readBuffer,
+		WriteBuffer:       *writeBuffer,
+		ReadLimit:         *readLimit,
+		AllowedOrigins:    *allowedOrigins,
+		Subprotocols:      append([]string{}, upgrader.Subprotocols...),
+		ShutdownTimeoutMs: shutdownTimeout.Milliseconds(),
+	}
+
 	hub := newHub()
 	go hub.run()
 
 	http.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
-		handleWebSocket(hub, w, r)
+		handleWebSocket(hub, cfg, w, r)
+	})
+
+	http.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
+		handleStats(hub, w, r)
+	})
+
+	http.HandleFunc("/config", func(w http.ResponseWriter, r *http.Request) {
+		handleConfig(serverCfg, w, r)
 	})
 
 	http.HandleFunc("/health", handleHealth)
+	http.HandleFunc("/livez", handleHealth)
+	http.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		handleReadyz(hub, w, r)
+	})
 
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "text/html")
 		w.Write([]byte(clientHTML))
 	})
 
-	if *tlsCert != "" && *tlsKey != "" {
-		log.Printf("Starting WSS server on %s", *addr)
-		log.Fatal(http.ListenAndServeTLS(*addr, *tlsCert, *tlsKey, nil))
-	} else {
-		log.Printf("Starting WS server on %s", *addr)
-		log.Fatal(http.ListenAndServe(*addr, nil))
+	server := &http.Server{Addr: *addr}
+	errc := make(chan error, 1)
+	go func() {
+		if tlsEnabled {
+			log.Printf("Starting WSS server on %s", *addr)
+			errc <- server.ListenAndServeTLS(*tlsCert, *tlsKey)
+		} else {
+			log.Printf("Starting WS server on %s", *addr)
+			errc <- server.ListenAndServe()
+		}
+	}()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	select {
+	case err := <-errc:
+		log.Fatal(err)
+	case <-ctx.Done():
+	}
+	stop()
+
+	// Stop accepting upgrades first. Upgraded connections are hijacked, so
+	// server.Shutdown does not wait for them; the hub closes those.
+	log.Printf("Shutting down, close timeout %s", *shutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("HTTP shutdown incomplete: %v", err)
 	}
+	hub.Shutdown(*shutdownTimeout)
+	log.Printf("Server stopped")
 }
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestBroadcastDuringFloodKeepsClient(t *testing.T) {
+	hub := newHub()
+	go hub.run()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleWebSocket(hub, wsConfig{}, w, r)
+	}))
+	defer srv.Close()
+
+	const room = "flood-test"
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/?room="+room, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	// Far more than the socket buffers hold, so with nobody reading the
+	// flood backs up into the client's send queue.
+	if err := conn.WriteMessage(websocket.TextMessage, []byte("flood:400:65536")); err != nil {
+		t.Fatalf("write: %v", err)
+	}
+
+	// Wait for the queue to fill as far as the flood takes it, so the
+	// broadcast below lands on a client that is as busy as it gets.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		hub.mu.RLock()
+		full := false
+		for c := range hub.rooms[room] {
+			full = len(c.send) >= cap(c.send)-floodHeadroom
+		}
+		hub.mu.RUnlock()
+		if full {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("flood never filled the send queue")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	hub.broadcast <- roomMessage{room: room, data: []byte("hello room")}
+
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	sawBroadcast := false
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("connection lost during flood (broadcast seen: %t): %v", sawBroadcast, err)
+		}
+		if string(msg) == "hello room" {
+			sawBroadcast = true
+		}
+		if strings.Contains(string(msg), `"flood_done"`) {
+			break
+		}
+	}
+	if !sawBroadcast {
+		t.Error("broadcast was not delivered during the flood")
+	}
+}